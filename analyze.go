@@ -0,0 +1,136 @@
+package stl
+
+// This file contains a topological analysis of a solid, summarizing
+// the results of Validate
+
+// AnalysisReport summarizes the topology of a solid, see Solid.Analyze.
+// It can be serialized using encoding/json.
+type AnalysisReport struct {
+	// Triangles is the number of triangles.
+	Triangles int `json:"triangles"`
+
+	// Vertices is the number of distinct vertices of non-degenerate triangles.
+	Vertices int `json:"vertices"`
+
+	// Edges is the number of distinct edges of non-degenerate triangles.
+	Edges int `json:"edges"`
+
+	// IsClosed is true if every edge is shared by at least two triangles.
+	IsClosed bool `json:"isClosed"`
+
+	// IsManifold is true if every edge is shared by at most two triangles with
+	// opposite orientation, and the triangles around every vertex form a
+	// single fan.
+	IsManifold bool `json:"isManifold"`
+
+	// EulerCharacteristic is Vertices - Edges + the number of non-degenerate triangles.
+	EulerCharacteristic int `json:"eulerCharacteristic"`
+
+	// Genus is the total number of handles, e.g. 0 for a sphere and 1 for a
+	// torus. Only meaningful for manifold solids, otherwise it is -1.
+	Genus int `json:"genus"`
+
+	// Shells is the number of groups of triangles connected by edges.
+	Shells int `json:"shells"`
+
+	// BoundaryLoops contains the number of edges of every boundary loop, i.e.
+	// every hole. Empty for closed solids.
+	BoundaryLoops []int `json:"boundaryLoops"`
+
+	// OpenEdges is the number of edges used by only one triangle.
+	OpenEdges int `json:"openEdges"`
+
+	// NonManifoldEdges is the number of edges used by more than two triangles,
+	// or by two triangles in the same direction.
+	NonManifoldEdges int `json:"nonManifoldEdges"`
+
+	// NonManifoldVertices is the number of vertices where the surface is
+	// pinched together.
+	NonManifoldVertices int `json:"nonManifoldVertices"`
+
+	// DegenerateTriangles is the number of triangles with equal vertices or
+	// zero area.
+	DegenerateTriangles int `json:"degenerateTriangles"`
+
+	// FlippedTriangles is the number of non-degenerate triangles whose normal
+	// vector does not match the orientation given by their vertices.
+	FlippedTriangles int `json:"flippedTriangles"`
+}
+
+// Analyze interprets the results of Validate, and adds information about the
+// topology of the solid. Vertices are matched exactly, like in Validate.
+func (s *Solid) Analyze() *AnalysisReport {
+	r := AnalysisReport{
+		Triangles:     len(s.Triangles),
+		BoundaryLoops: []int{},
+	}
+
+	degenerate := make([]bool, len(s.Triangles))
+	for i := range s.Triangles {
+		degenerate[i] = s.Triangles[i].Area() == 0
+	}
+	for i, te := range s.Validate() {
+		if te.HasEqualVertices {
+			degenerate[i] = true
+		} else if te.NormalDoesNotMatch && !degenerate[i] {
+			r.FlippedTriangles++
+		}
+	}
+	for _, d := range degenerate {
+		if d {
+			r.DegenerateTriangles++
+		}
+	}
+
+	m := NewHalfEdgeMesh(s, 0)
+	usedVertices := make(map[int]bool)
+	edges := make(map[[2]uint32]bool)
+	faces := 0
+	for f, face := range m.Faces {
+		if m.isDegenerateFace(f) {
+			continue
+		}
+		faces++
+		for v := 0; v < 3; v++ {
+			usedVertices[int(face[v])] = true
+			edges[undirectedEdge(face[v], face[(v+1)%3])] = true
+		}
+	}
+	r.Vertices = len(usedVertices)
+	r.Edges = len(edges)
+	r.EulerCharacteristic = r.Vertices - r.Edges + faces
+
+	nonManifoldEdges := make(map[[2]uint32]bool)
+	for h, he := range m.HalfEdges {
+		if m.IsBoundary(h) {
+			r.OpenEdges++
+		} else if m.IsNonManifold(h) {
+			nonManifoldEdges[undirectedEdge(uint32(he.Vertex), uint32(m.Target(h)))] = true
+		}
+	}
+	r.NonManifoldEdges = len(nonManifoldEdges)
+	r.NonManifoldVertices = len(m.NonManifoldVertices())
+	r.IsClosed = r.OpenEdges == 0
+	r.IsManifold = r.NonManifoldEdges == 0 && r.NonManifoldVertices == 0
+
+	for _, loop := range m.BoundaryLoops() {
+		r.BoundaryLoops = append(r.BoundaryLoops, len(loop))
+	}
+
+	labels, _ := faceComponents(&m.IndexedMesh)
+	shells := make(map[int]bool)
+	for f, label := range labels {
+		if !m.isDegenerateFace(f) {
+			shells[label] = true
+		}
+	}
+	r.Shells = len(shells)
+
+	// For orientable manifolds with b boundary loops:
+	//   EulerCharacteristic = 2 * Shells - 2 * Genus - b
+	r.Genus = -1
+	if r.IsManifold {
+		r.Genus = (2*r.Shells - len(r.BoundaryLoops) - r.EulerCharacteristic) / 2
+	}
+	return &r
+}
@@ -0,0 +1,68 @@
+package stl
+
+// Tests for the topological analysis of solids
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeClosed(t *testing.T) {
+	r := makeTestCube(Vec3{0, 0, 0}, 1).Analyze()
+	expected := AnalysisReport{
+		Triangles:           12,
+		Vertices:            8,
+		Edges:               18,
+		IsClosed:            true,
+		IsManifold:          true,
+		EulerCharacteristic: 2,
+		Genus:               0,
+		Shells:              1,
+		BoundaryLoops:       []int{},
+	}
+	if !reflect.DeepEqual(*r, expected) {
+		t.Errorf("expected %+v, got %+v", expected, *r)
+	}
+
+	r = makeTestTorus(4, 1, 12, 8).Analyze()
+	if !r.IsClosed || !r.IsManifold || r.EulerCharacteristic != 0 || r.Genus != 1 || r.Shells != 1 {
+		t.Errorf("unexpected torus analysis: %+v", *r)
+	}
+
+	twoCubes := makeTestCube(Vec3{0, 0, 0}, 1)
+	twoCubes.Triangles = append(twoCubes.Triangles, makeTestCube(Vec3{2, 0, 0}, 1).Triangles...)
+	r = twoCubes.Analyze()
+	if r.Shells != 2 || r.EulerCharacteristic != 4 || r.Genus != 0 {
+		t.Errorf("unexpected analysis of two cubes: %+v", *r)
+	}
+}
+
+func TestAnalyzeBroken(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = s.Triangles[1:]
+	s.Triangles[0].Normal = s.Triangles[0].Normal.MultScalar(-1)
+	s.Triangles = append(s.Triangles, Triangle{Vertices: [3]Vec3{{0, 0, 0}, {1, 1, 1}, {0, 0, 0}}})
+	r := s.Analyze()
+	if r.IsClosed || !r.IsManifold || r.OpenEdges != 3 || !reflect.DeepEqual(r.BoundaryLoops, []int{3}) {
+		t.Errorf("expected one hole with 3 edges: %+v", *r)
+	}
+	if r.DegenerateTriangles != 1 || r.FlippedTriangles != 1 {
+		t.Errorf("expected one degenerate and one flipped triangle: %+v", *r)
+	}
+	if r.Genus != 0 {
+		t.Errorf("expected genus 0: %+v", *r)
+	}
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded AnalysisReport
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(decoded, *r) {
+		t.Errorf("JSON round trip failed: %s", data)
+	}
+}
@@ -0,0 +1,557 @@
+package stl
+
+// This file defines the BVH data type, a bounding volume hierarchy for
+// spatial queries on the triangles of a solid
+
+import (
+	"math"
+	"sort"
+)
+
+// BVHSplit selects how the triangles of a BVH node are divided among its children.
+type BVHSplit int
+
+const (
+	// BVHSplitSAH minimizes the surface area heuristic, i.e. the expected
+	// cost of ray queries. Building takes a bit longer, queries are faster.
+	BVHSplitSAH BVHSplit = iota
+
+	// BVHSplitMedian splits at the median of the triangle centroids along
+	// the longest axis.
+	BVHSplitMedian
+)
+
+// BVHOptions control NewBVHWithOptions.
+type BVHOptions struct {
+	// LeafSize is the maximum number of triangles in a leaf node. The
+	// default 0 means 4.
+	LeafSize int
+
+	// Split selects how nodes are split.
+	Split BVHSplit
+}
+
+// BVH is a bounding volume hierarchy of the triangles of a solid, a binary
+// tree of axis aligned boxes, allowing to find the triangles near a box, a
+// ray, or a point without testing every triangle. It keeps a copy of the
+// vertices, so changing the solid afterwards does not affect it. A BVH is not
+// modified by queries, so it can be queried from many goroutines at once.
+type BVH struct {
+	triangles []triangle64
+
+	// order contains the triangle indices, the triangles of each leaf
+	// are in a contiguous range
+	order []int
+
+	// nodes are in depth first order, the left child of an inner node
+	// directly follows it
+	nodes []bvhNode
+}
+
+// bvhNode is a node of a BVH. For a leaf, count > 0 and the triangles are
+// order[first:first+count]. For an inner node, count == 0 and its right
+// child is nodes[right].
+type bvhNode struct {
+	box          box64
+	first, count int
+	right        int
+}
+
+// RayHit describes where a ray hits a triangle.
+type RayHit struct {
+	// Triangle is the index of the triangle in Solid.Triangles.
+	Triangle int
+
+	// Distance is the distance from the ray origin to the hit point, in
+	// multiples of the length of the ray direction.
+	Distance float64
+
+	// Point is the hit point.
+	Point Vec3
+
+	// U and V are the barycentric coordinates of the hit point, the weights
+	// of the vertices 1 and 2 of the triangle. The weight of vertex 0 is 1-U-V.
+	U, V float64
+}
+
+// NewBVH builds a BVH of the triangles of s, using the default options.
+func NewBVH(s *Solid) *BVH {
+	return NewBVHWithOptions(s, BVHOptions{})
+}
+
+// NewBVHWithOptions builds a BVH of the triangles of s.
+func NewBVHWithOptions(s *Solid, options BVHOptions) *BVH {
+	triangles := make([]triangle64, len(s.Triangles))
+	order := make([]int, len(s.Triangles))
+	for i := range s.Triangles {
+		for v := 0; v < 3; v++ {
+			triangles[i][v] = toVec64(s.Triangles[i].Vertices[v])
+		}
+		order[i] = i
+	}
+	return newBVH(triangles, order, options)
+}
+
+// newBVH builds a BVH of the triangles with the indices in order, which is
+// reordered and kept by the BVH, like triangles.
+func newBVH(triangles []triangle64, order []int, options BVHOptions) *BVH {
+	leafSize := options.LeafSize
+	if leafSize <= 0 {
+		leafSize = 4
+	}
+	b := &BVH{triangles: triangles, order: order}
+	boxes := make([]box64, len(triangles))
+	centroids := make([]vec64, len(triangles))
+	for _, i := range order {
+		boxes[i] = triangles[i].box()
+		centroids[i] = boxes[i].min.add(boxes[i].max).scale(0.5)
+	}
+	if len(order) > 0 {
+		builder := bvhBuilder{bvh: b, boxes: boxes, centroids: centroids, leafSize: leafSize, split: options.Split}
+		builder.build(0, len(order))
+	}
+	return b
+}
+
+// bvhBuilder holds the data needed while building a BVH.
+type bvhBuilder struct {
+	bvh       *BVH
+	boxes     []box64
+	centroids []vec64
+	leafSize  int
+	split     BVHSplit
+}
+
+// build adds the node for the triangles in order[first:end], and its
+// children, returning its index.
+func (bb *bvhBuilder) build(first, end int) int {
+	b := bb.bvh
+	idx := len(b.nodes)
+	b.nodes = append(b.nodes, bvhNode{})
+	box := bb.boxes[b.order[first]]
+	centroidBox := box64{bb.centroids[b.order[first]], bb.centroids[b.order[first]]}
+	for _, i := range b.order[first:end] {
+		box.extend(bb.boxes[i].min)
+		box.extend(bb.boxes[i].max)
+		centroidBox.extend(bb.centroids[i])
+	}
+	b.nodes[idx].box = box
+
+	n := end - first
+	mid := -1
+	if n > bb.leafSize {
+		if bb.split == BVHSplitSAH {
+			mid = bb.sahSplit(first, end, centroidBox)
+		}
+		if mid < 0 && centroidBox.maxExtent() > 0 {
+			mid = bb.medianSplit(first, end, centroidBox)
+		}
+	}
+	if mid <= first || mid >= end {
+		b.nodes[idx].first, b.nodes[idx].count = first, n
+		return idx
+	}
+	bb.build(first, mid)
+	right := bb.build(mid, end)
+	b.nodes[idx].right = right
+	return idx
+}
+
+// longestAxis returns the axis along which b is longest.
+func (b *box64) longestAxis() int {
+	return dominantAxis(b.max.sub(b.min))
+}
+
+// medianSplit sorts order[first:end] along the longest axis of the centroids,
+// and returns the middle index.
+func (bb *bvhBuilder) medianSplit(first, end int, centroidBox box64) int {
+	axis := centroidBox.longestAxis()
+	part := bb.bvh.order[first:end]
+	sort.Slice(part, func(a, b int) bool {
+		return bb.centroids[part[a]][axis] < bb.centroids[part[b]][axis]
+	})
+	return first + len(part)/2
+}
+
+// area returns half the surface area of b.
+func (b *box64) area() float64 {
+	d := b.max.sub(b.min)
+	return d[0]*d[1] + d[1]*d[2] + d[2]*d[0]
+}
+
+// sahSplit partitions order[first:end] by the binned surface area heuristic
+// along the longest axis of the centroids, and returns the index where the
+// second child starts, or -1 if there is no split with triangles on both sides.
+func (bb *bvhBuilder) sahSplit(first, end int, centroidBox box64) int {
+	const bins = 16
+	axis := centroidBox.longestAxis()
+	lo, extent := centroidBox.min[axis], centroidBox.max[axis]-centroidBox.min[axis]
+	if extent <= 0 {
+		return -1
+	}
+	part := bb.bvh.order[first:end]
+	binOf := func(i int) int {
+		bin := int(bins * (bb.centroids[i][axis] - lo) / extent)
+		if bin >= bins {
+			bin = bins - 1
+		}
+		return bin
+	}
+
+	var counts [bins]int
+	var boxes [bins]box64
+	for _, i := range part {
+		bin := binOf(i)
+		if counts[bin] == 0 {
+			boxes[bin] = bb.boxes[i]
+		} else {
+			boxes[bin].extend(bb.boxes[i].min)
+			boxes[bin].extend(bb.boxes[i].max)
+		}
+		counts[bin]++
+	}
+
+	// costs of all splits between bin k-1 and bin k, sweeping from both sides
+	var leftCost [bins]float64
+	var acc box64
+	count := 0
+	for k := 1; k < bins; k++ {
+		if counts[k-1] > 0 {
+			if count == 0 {
+				acc = boxes[k-1]
+			} else {
+				acc.extend(boxes[k-1].min)
+				acc.extend(boxes[k-1].max)
+			}
+			count += counts[k-1]
+		}
+		leftCost[k] = acc.area() * float64(count)
+	}
+	best, bestCost := -1, math.Inf(1)
+	count = 0
+	for k := bins - 1; k >= 1; k-- {
+		if counts[k] > 0 {
+			if count == 0 {
+				acc = boxes[k]
+			} else {
+				acc.extend(boxes[k].min)
+				acc.extend(boxes[k].max)
+			}
+			count += counts[k]
+		}
+		if count == 0 || count == len(part) {
+			continue
+		}
+		if cost := leftCost[k] + acc.area()*float64(count); cost < bestCost {
+			best, bestCost = k, cost
+		}
+	}
+	if best < 0 {
+		return -1
+	}
+
+	// partition in place
+	i, j := 0, len(part)-1
+	for i <= j {
+		if binOf(part[i]) < best {
+			i++
+		} else {
+			part[i], part[j] = part[j], part[i]
+			j--
+		}
+	}
+	return first + i
+}
+
+// QueryBox calls fn with the index of every triangle whose bounding box
+// overlaps the box from min to max, until fn returns false. The triangles are
+// visited in no particular order.
+func (b *BVH) QueryBox(min, max Vec3, fn func(triangle int) bool) {
+	query := box64{toVec64(min), toVec64(max)}
+	b.traverse(func(box *box64) bool {
+		return box.overlaps(query)
+	}, func(i int) bool {
+		box := b.triangles[i].box()
+		return !box.overlaps(query) || fn(i)
+	})
+}
+
+// BoxTriangles returns the ascending indices of all triangles whose bounding
+// box overlaps the box from min to max.
+func (b *BVH) BoxTriangles(min, max Vec3) []int {
+	var found []int
+	b.QueryBox(min, max, func(i int) bool {
+		found = append(found, i)
+		return true
+	})
+	sort.Ints(found)
+	return found
+}
+
+// traverse visits all nodes whose box is accepted by enter, and calls fn for
+// the triangles of the leaves, until fn returns false.
+func (b *BVH) traverse(enter func(box *box64) bool, fn func(triangle int) bool) {
+	if len(b.nodes) == 0 {
+		return
+	}
+	stack := make([]int, 1, 64)
+	for len(stack) > 0 {
+		idx := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		node := &b.nodes[idx]
+		if !enter(&node.box) {
+			continue
+		}
+		if node.count > 0 {
+			for _, i := range b.order[node.first : node.first+node.count] {
+				if !fn(i) {
+					return
+				}
+			}
+			continue
+		}
+		stack = append(stack, node.right, idx+1)
+	}
+}
+
+// FirstHit returns where the ray from origin into direction hits a
+// triangle first. ok is false if it hits none. Triangles are hit from both
+// sides, and also when the ray passes exactly through an edge or vertex.
+func (b *BVH) FirstHit(origin, direction Vec3) (hit RayHit, ok bool) {
+	o, d := toVec64(origin), toVec64(direction)
+	r := newRay64(o, d)
+	hit.Distance = math.Inf(1)
+	b.traverse(func(box *box64) bool {
+		near, ok := r.boxEntry(box)
+		return ok && near <= hit.Distance
+	}, func(i int) bool {
+		if t, u, v, found := r.intersect(&b.triangles[i]); found && t < hit.Distance {
+			hit = RayHit{Triangle: i, Distance: t, U: u, V: v}
+			ok = true
+		}
+		return true
+	})
+	if ok {
+		hit.Point = o.add(d.scale(hit.Distance)).vec3()
+	}
+	return hit, ok
+}
+
+// AllHits returns all places where the ray from origin into direction hits a
+// triangle, ordered by distance, see FirstHit. A ray passing exactly through
+// an edge or vertex hits all triangles sharing it.
+func (b *BVH) AllHits(origin, direction Vec3) []RayHit {
+	o, d := toVec64(origin), toVec64(direction)
+	r := newRay64(o, d)
+	var hits []RayHit
+	b.traverse(func(box *box64) bool {
+		_, ok := r.boxEntry(box)
+		return ok
+	}, func(i int) bool {
+		if t, u, v, found := r.intersect(&b.triangles[i]); found {
+			hits = append(hits, RayHit{Triangle: i, Distance: t, Point: o.add(d.scale(t)).vec3(), U: u, V: v})
+		}
+		return true
+	})
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].Triangle < hits[j].Triangle
+	})
+	return hits
+}
+
+// ray64 is a ray in double precision.
+type ray64 struct {
+	origin, direction, inverse vec64
+}
+
+func newRay64(origin, direction vec64) ray64 {
+	r := ray64{origin: origin, direction: direction}
+	for i := 0; i < 3; i++ {
+		r.inverse[i] = 1 / direction[i]
+	}
+	return r
+}
+
+// boxEntry returns the distance at which r enters box, or 0 if its origin
+// is inside. ok is false if r misses box.
+func (r *ray64) boxEntry(box *box64) (near float64, ok bool) {
+	near, far := 0.0, math.Inf(1)
+	for i := 0; i < 3; i++ {
+		if r.direction[i] == 0 {
+			if r.origin[i] < box.min[i] || r.origin[i] > box.max[i] {
+				return 0, false
+			}
+			continue
+		}
+		t1 := (box.min[i] - r.origin[i]) * r.inverse[i]
+		t2 := (box.max[i] - r.origin[i]) * r.inverse[i]
+		if t1 > t2 {
+			t1, t2 = t2, t1
+		}
+		near = math.Max(near, t1)
+		far = math.Min(far, t2)
+		if near > far {
+			return 0, false
+		}
+	}
+	return near, true
+}
+
+// intersect returns the distance t and the barycentric coordinates u, v of
+// the point where r hits triangle tr, using the Möller–Trumbore algorithm.
+// found is false if r misses tr, or runs parallel to it.
+func (r *ray64) intersect(tr *triangle64) (t, u, v float64, found bool) {
+	e1, e2 := tr[1].sub(tr[0]), tr[2].sub(tr[0])
+	p := r.direction.cross(e2)
+	det := e1.dot(p)
+	if det == 0 {
+		return 0, 0, 0, false
+	}
+	inv := 1 / det
+	s := r.origin.sub(tr[0])
+	u = s.dot(p) * inv
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+	q := s.cross(e1)
+	v = r.direction.dot(q) * inv
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+	t = e2.dot(q) * inv
+	if t < 0 {
+		return 0, 0, 0, false
+	}
+	return t, u, v, true
+}
+
+// Nearest returns the index of the triangle closest to p, the closest point
+// on it, and its distance to p. For a BVH without triangles, triangle is -1.
+func (b *BVH) Nearest(p Vec3) (triangle int, point Vec3, dist float64) {
+	q := toVec64(p)
+	triangle, closest, distSquared := b.nearest(q)
+	if triangle < 0 {
+		return -1, Vec3{}, math.Inf(1)
+	}
+	return triangle, closest.vec3(), math.Sqrt(distSquared)
+}
+
+// nearest returns the index of the triangle closest to p, the closest point
+// on it, and the square of its distance to p. Nodes are visited closest first,
+// skipping those farther away than the closest triangle found so far.
+func (b *BVH) nearest(p vec64) (triangle int, closest vec64, distSquared float64) {
+	triangle, distSquared = -1, math.Inf(1)
+	if len(b.nodes) == 0 {
+		return
+	}
+	type entry struct {
+		node int
+		dist float64
+	}
+	stack := []entry{{0, b.nodes[0].box.distSquared(p)}}
+	for len(stack) > 0 {
+		e := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if e.dist >= distSquared {
+			continue
+		}
+		node := &b.nodes[e.node]
+		if node.count > 0 {
+			for _, i := range b.order[node.first : node.first+node.count] {
+				c := closestPointOnTriangle(p, &b.triangles[i])
+				if d := c.sub(p); d.dot(d) < distSquared {
+					triangle, closest, distSquared = i, c, d.dot(d)
+				}
+			}
+			continue
+		}
+		left, right := e.node+1, node.right
+		dl, dr := b.nodes[left].box.distSquared(p), b.nodes[right].box.distSquared(p)
+		// push the farther child first, so the closer one is visited first
+		if dl < dr {
+			stack = append(stack, entry{right, dr}, entry{left, dl})
+		} else {
+			stack = append(stack, entry{left, dl}, entry{right, dr})
+		}
+	}
+	return
+}
+
+// distSquared returns the square of the distance of p from b, 0 if p is inside.
+func (b *box64) distSquared(p vec64) float64 {
+	var d float64
+	for i := 0; i < 3; i++ {
+		if p[i] < b.min[i] {
+			d += (b.min[i] - p[i]) * (b.min[i] - p[i])
+		} else if p[i] > b.max[i] {
+			d += (p[i] - b.max[i]) * (p[i] - b.max[i])
+		}
+	}
+	return d
+}
+
+// closestPointOnTriangle returns the point of triangle t closest to p.
+func closestPointOnTriangle(p vec64, t *triangle64) vec64 {
+	c, _ := closestTriangleFeature(p, t)
+	return c
+}
+
+// Features of a triangle the closest point can lie on, as returned by
+// closestTriangleFeature. The vertices are 0, 1, and 2.
+const (
+	featureEdge01 = 3 + iota
+	featureEdge12
+	featureEdge20
+	featureFace
+)
+
+// closestTriangleFeature returns the point of triangle t closest to p, and
+// the feature it lies on, by determining the Voronoi region of p, as
+// described in Christer Ericson, "Real-Time Collision Detection".
+func closestTriangleFeature(p vec64, t *triangle64) (vec64, int) {
+	a, b, c := t[0], t[1], t[2]
+	ab, ac, ap := b.sub(a), c.sub(a), p.sub(a)
+	d1, d2 := ab.dot(ap), ac.dot(ap)
+	if d1 <= 0 && d2 <= 0 {
+		return a, 0
+	}
+	bp := p.sub(b)
+	d3, d4 := ab.dot(bp), ac.dot(bp)
+	if d3 >= 0 && d4 <= d3 {
+		return b, 1
+	}
+	vc := d1*d4 - d3*d2
+	if vc <= 0 && d1 >= 0 && d3 <= 0 {
+		return a.add(ab.scale(d1 / (d1 - d3))), featureEdge01
+	}
+	cp := p.sub(c)
+	d5, d6 := ab.dot(cp), ac.dot(cp)
+	if d6 >= 0 && d5 <= d6 {
+		return c, 2
+	}
+	vb := d5*d2 - d1*d6
+	if vb <= 0 && d2 >= 0 && d6 <= 0 {
+		return a.add(ac.scale(d2 / (d2 - d6))), featureEdge20
+	}
+	va := d3*d6 - d5*d4
+	if va <= 0 && d4-d3 >= 0 && d5-d6 >= 0 {
+		return b.add(c.sub(b).scale((d4 - d3) / ((d4 - d3) + (d5 - d6)))), featureEdge12
+	}
+	denom := va + vb + vc
+	if denom == 0 {
+		// degenerate triangle, the closest of its vertices is good enough
+		best, bestDist := 0, math.Inf(1)
+		for v := 0; v < 3; v++ {
+			if d := t[v].sub(p); d.dot(d) < bestDist {
+				best, bestDist = v, d.dot(d)
+			}
+		}
+		return t[best], best
+	}
+	v := vb / denom
+	w := vc / denom
+	return a.add(ab.scale(v)).add(ac.scale(w)), featureFace
+}
@@ -0,0 +1,134 @@
+package stl
+
+// Tests for the BVH data type
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestBVHQueries(t *testing.T) {
+	s := makeTestTorus(2, 0.5, 32, 16)
+	rnd := rand.New(rand.NewSource(1))
+	randomVec := func(scale float64) Vec3 {
+		return Vec3{
+			float32(scale * (2*rnd.Float64() - 1)),
+			float32(scale * (2*rnd.Float64() - 1)),
+			float32(scale * (2*rnd.Float64() - 1)),
+		}
+	}
+	triangles := make([]triangle64, len(s.Triangles))
+	for i := range s.Triangles {
+		for v := 0; v < 3; v++ {
+			triangles[i][v] = toVec64(s.Triangles[i].Vertices[v])
+		}
+	}
+
+	for _, options := range []BVHOptions{{}, {LeafSize: 1, Split: BVHSplitMedian}, {LeafSize: 100}} {
+		b := NewBVHWithOptions(s, options)
+		for q := 0; q < 50; q++ {
+			// box query
+			min, max := randomVec(3), randomVec(3)
+			for d := 0; d < 3; d++ {
+				min[d], max[d] = float32(math.Min(float64(min[d]), float64(max[d]))), float32(math.Max(float64(min[d]), float64(max[d])))
+			}
+			query := box64{toVec64(min), toVec64(max)}
+			var expected []int
+			for i := range triangles {
+				if box := triangles[i].box(); box.overlaps(query) {
+					expected = append(expected, i)
+				}
+			}
+			if found := b.BoxTriangles(min, max); !reflect.DeepEqual(found, expected) {
+				t.Errorf("%+v: box query found %v, expected %v", options, found, expected)
+			}
+
+			// ray queries
+			origin, direction := randomVec(4), randomVec(1)
+			r := newRay64(toVec64(origin), toVec64(direction))
+			expectedHits := 0
+			expectedFirst := math.Inf(1)
+			for i := range triangles {
+				if d, _, _, found := r.intersect(&triangles[i]); found {
+					expectedHits++
+					expectedFirst = math.Min(expectedFirst, d)
+				}
+			}
+			hits := b.AllHits(origin, direction)
+			if len(hits) != expectedHits {
+				t.Errorf("%+v: expected %d hits, got %d", options, expectedHits, len(hits))
+			}
+			first, ok := b.FirstHit(origin, direction)
+			if ok != (expectedHits > 0) || (ok && (first.Distance != expectedFirst || first != hits[0])) {
+				t.Errorf("%+v: unexpected first hit %+v, expected distance %g", options, first, expectedFirst)
+			}
+
+			// nearest triangle
+			p := randomVec(4)
+			expectedDist := math.Inf(1)
+			for i := range triangles {
+				c := closestPointOnTriangle(toVec64(p), &triangles[i])
+				expectedDist = math.Min(expectedDist, c.sub(toVec64(p)).len())
+			}
+			if _, _, dist := b.Nearest(p); !almostEqual64(dist, expectedDist, 1e-12) {
+				t.Errorf("%+v: expected nearest distance %g, got %g", options, expectedDist, dist)
+			}
+		}
+	}
+}
+
+func TestBVHRayHit(t *testing.T) {
+	b := NewBVH(makeTestCube(Vec3{0, 0, 0}, 1))
+	hit, ok := b.FirstHit(Vec3{0.25, 0.5, -1}, Vec3{0, 0, 2})
+	if !ok {
+		t.Fatal("expected ray to hit cube")
+	}
+	if hit.Distance != 0.5 || hit.Point != (Vec3{0.25, 0.5, 0}) || (hit.Triangle != 8 && hit.Triangle != 9) {
+		t.Errorf("unexpected hit %+v", hit)
+	}
+	tr := b.triangles[hit.Triangle]
+	p := tr[0].scale(1 - hit.U - hit.V).add(tr[1].scale(hit.U)).add(tr[2].scale(hit.V))
+	if p.vec3() != hit.Point {
+		t.Errorf("barycentric coordinates of %+v result in %v", hit, p)
+	}
+	if hits := b.AllHits(Vec3{0.25, 0.5, -1}, Vec3{0, 0, 1}); len(hits) != 2 || hits[1].Distance != 2 {
+		t.Errorf("expected hits at distance 1 and 2, got %+v", hits)
+	}
+	if _, ok := b.FirstHit(Vec3{2, 2, 2}, Vec3{1, 0, 0}); ok {
+		t.Error("unexpected hit of ray pointing away")
+	}
+}
+
+func TestBVHNearest(t *testing.T) {
+	b := NewBVH(makeTestCube(Vec3{0, 0, 0}, 1))
+	_, point, dist := b.Nearest(Vec3{2, 0.5, 0.5})
+	if point != (Vec3{1, 0.5, 0.5}) || dist != 1 {
+		t.Errorf("expected closest point {1, 0.5, 0.5} at distance 1, got %v at %g", point, dist)
+	}
+	if triangle, _, _ := NewBVH(&Solid{}).Nearest(Vec3{}); triangle != -1 {
+		t.Errorf("expected -1 for empty BVH, got %d", triangle)
+	}
+}
+
+func TestBVHConcurrent(t *testing.T) {
+	s := makeTestTorus(2, 0.5, 32, 16)
+	b := NewBVH(s)
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				angle := float64(g*100+i) / 100
+				p := Vec3{float32(3 * math.Cos(angle)), float32(3 * math.Sin(angle)), 0}
+				if _, _, dist := b.Nearest(p); !almostEqual64(dist, 0.5, 0.02) {
+					t.Errorf("unexpected distance %g", dist)
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,184 @@
+package stl
+
+// This file contains comparing the surfaces of two solids
+
+import (
+	"math"
+)
+
+// CompareOptions control Compare.
+type CompareOptions struct {
+	// SampleSpacing is the maximum distance between the points sampled on
+	// every triangle. Smaller values give more accurate results, but take
+	// longer. The default 0 means 1/100 of the diagonal of the bounding box
+	// of both solids.
+	SampleSpacing float64
+
+	// Sign selects how the sign of deviations is determined.
+	Sign SignMode
+}
+
+// CompareResult is the result of Compare.
+type CompareResult struct {
+	// AToB describes how far the surface of a is away from b, BToA the
+	// other way around.
+	AToB, BToA Deviation
+
+	// Hausdorff is the symmetric Hausdorff distance, the larger of
+	// AToB.Hausdorff and BToA.Hausdorff.
+	Hausdorff float64
+}
+
+// Deviation describes how far the surface of one solid is away from the
+// surface of another one.
+type Deviation struct {
+	// Hausdorff is the one-sided Hausdorff distance, the largest distance of
+	// a point on the first surface from the second surface.
+	Hausdorff float64
+
+	// Mean is the mean distance of the points on the first surface from the
+	// second surface, weighted by area.
+	Mean float64
+
+	// RMS is the root mean square distance of the points on the first
+	// surface from the second surface, weighted by area.
+	RMS float64
+
+	// Triangles contains for every triangle of the first solid the signed
+	// distance of its point farthest from the second solid, positive outside
+	// of it, and negative inside.
+	Triangles []float64
+}
+
+// Compare measures how far the surfaces of a and b are apart, by sampling
+// points on the triangles of either solid, and finding their distance to the
+// other one, see DistanceQuery. The results are exact up to the sample
+// spacing. Signs are only meaningful if the solids are closed. If one of the
+// solids has no triangles, the distances from the other one are infinite.
+func Compare(a, b *Solid, options CompareOptions) CompareResult {
+	spacing := options.SampleSpacing
+	if spacing <= 0 {
+		inf := math.Inf(1)
+		box := box64{vec64{inf, inf, inf}, vec64{-inf, -inf, -inf}}
+		for _, s := range []*Solid{a, b} {
+			for i := range s.Triangles {
+				for _, v := range s.Triangles[i].Vertices {
+					box.extend(toVec64(v))
+				}
+			}
+		}
+		spacing = box.max.sub(box.min).len() / 100
+	}
+	var r CompareResult
+	r.AToB = deviation(a, NewDistanceQueryWithOptions(b, DistanceOptions{Sign: options.Sign}), spacing)
+	r.BToA = deviation(b, NewDistanceQueryWithOptions(a, DistanceOptions{Sign: options.Sign}), spacing)
+	r.Hausdorff = math.Max(r.AToB.Hausdorff, r.BToA.Hausdorff)
+	return r
+}
+
+// deviation samples the triangles of s on a grid with at most the given
+// spacing, and measures their distance to the triangles of q. The maximum is
+// taken over all grid points, the mean over the centres of the grid cells.
+func deviation(s *Solid, q *DistanceQuery, spacing float64) Deviation {
+	d := Deviation{Triangles: make([]float64, len(s.Triangles))}
+	areas := make([]float64, len(s.Triangles))
+	sums := make([]float64, len(s.Triangles))
+	squareSums := make([]float64, len(s.Triangles))
+	parallelChunks(len(s.Triangles), 64, func(first, end int) {
+		for i := first; i < end; i++ {
+			t := s.Triangles[i].triangle64()
+			areas[i] = t.normal().len() / 2
+			var farthest float64
+			sample := func(p vec64, weight float64) {
+				dist := q.signedDistance(p)
+				if math.Abs(dist) > math.Abs(farthest) {
+					farthest = dist
+				}
+				if weight > 0 {
+					sums[i] += weight * math.Abs(dist)
+					squareSums[i] += weight * dist * dist
+				}
+			}
+			t.sampleGrid(spacing, sample)
+			d.Triangles[i] = farthest
+		}
+	})
+
+	var area float64
+	for i := range s.Triangles {
+		d.Hausdorff = math.Max(d.Hausdorff, math.Abs(d.Triangles[i]))
+		area += areas[i]
+		d.Mean += areas[i] * sums[i]
+		d.RMS += areas[i] * squareSums[i]
+	}
+	if area > 0 {
+		d.Mean /= area
+		d.RMS = math.Sqrt(d.RMS / area)
+	}
+	return d
+}
+
+// sampleGrid divides t into k*k congruent triangles, with k chosen so that
+// their edges are not longer than spacing, and calls fn for all of their
+// vertices with weight 0, and for all of their centroids with weight 1/k².
+func (t *triangle64) sampleGrid(spacing float64, fn func(p vec64, weight float64)) {
+	ab, ac := t[1].sub(t[0]), t[2].sub(t[0])
+	longest := math.Max(math.Max(ab.len(), ac.len()), t[2].sub(t[1]).len())
+	k := 1
+	if longest > spacing {
+		k = int(math.Ceil(longest / spacing))
+	}
+	point := func(i, j float64) vec64 {
+		return t[0].add(ab.scale(i / float64(k))).add(ac.scale(j / float64(k)))
+	}
+	weight := 1 / float64(k*k)
+	for i := 0; i <= k; i++ {
+		for j := 0; i+j <= k; j++ {
+			fn(point(float64(i), float64(j)), 0)
+			if i+j < k {
+				fn(point(float64(i)+1.0/3, float64(j)+1.0/3), weight)
+			}
+			if i+j < k-1 {
+				fn(point(float64(i)+2.0/3, float64(j)+2.0/3), weight)
+			}
+		}
+	}
+}
+
+// Colorize writes the Triangles of d as colours into the attributes of the
+// triangles of s, which has to be the solid the deviations were measured
+// for, so that s can be saved as a heat map. Deviations of 0 are green,
+// positive ones turn red, and negative ones blue, reaching full saturation at
+// maxDeviation. For maxDeviation <= 0 the largest deviation is used.
+//
+// The colours are written as used by VisCAM and SolidView, with bit 15 set,
+// and 5 bits of red, green, and blue in bits 10 to 14, 5 to 9, and 0 to 4.
+// As ASCII STL files have no attributes, s has to be written in binary format.
+func (d *Deviation) Colorize(s *Solid, maxDeviation float64) {
+	if maxDeviation <= 0 {
+		for _, dev := range d.Triangles {
+			maxDeviation = math.Max(maxDeviation, math.Abs(dev))
+		}
+	}
+	for i := 0; i < len(s.Triangles) && i < len(d.Triangles); i++ {
+		s.Triangles[i].Attributes = deviationColor(d.Triangles[i], maxDeviation)
+	}
+}
+
+// deviationColor returns the colour of deviation dev, see Deviation.Colorize.
+func deviationColor(dev, maxDeviation float64) uint16 {
+	var x float64
+	if maxDeviation > 0 {
+		x = math.Max(-1, math.Min(1, dev/maxDeviation))
+	}
+	var r, g, b float64
+	if x >= 0 {
+		r, g = x, 1-x
+	} else {
+		g, b = 1+x, -x
+	}
+	channel := func(c float64) uint16 {
+		return uint16(math.Round(c * 31))
+	}
+	return 1<<15 | channel(r)<<10 | channel(g)<<5 | channel(b)
+}
@@ -0,0 +1,74 @@
+package stl
+
+// Tests for comparing solids
+
+import (
+	"math"
+	"testing"
+)
+
+func TestCompareShifted(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	b := makeTestCube(Vec3{0, 0, 0.1}, 1)
+	r := Compare(a, b, CompareOptions{SampleSpacing: 0.02})
+	if !almostEqual64(r.Hausdorff, 0.1, 1e-6) || !almostEqual64(r.AToB.Hausdorff, 0.1, 1e-6) || !almostEqual64(r.BToA.Hausdorff, 0.1, 1e-6) {
+		t.Errorf("expected Hausdorff distances 0.1, got %g, %g, %g", r.Hausdorff, r.AToB.Hausdorff, r.BToA.Hausdorff)
+	}
+	// the bottom deviates by 0.1, the sides by 0.1-z for z < 0.1, and the
+	// top by 0.1, or the distance t to its border if t < 0.1
+	top := 0.64*0.1 + 4*(0.1*0.1/2-2*0.1*0.1*0.1/3)
+	mean := (0.1 + top + 4*0.005) / 6
+	if !almostEqual64(r.AToB.Mean, mean, 1e-3) || !almostEqual64(r.BToA.Mean, mean, 1e-3) {
+		t.Errorf("expected mean deviations %g, got %g, %g", mean, r.AToB.Mean, r.BToA.Mean)
+	}
+	topSquared := 0.64*0.01 + 4*(0.1*0.1*0.1/3-2*0.1*0.1*0.1*0.1/4)
+	rms := math.Sqrt((0.01 + topSquared + 4*0.001/3) / 6)
+	if !almostEqual64(r.AToB.RMS, rms, 1e-3) {
+		t.Errorf("expected RMS deviation %g, got %g", rms, r.AToB.RMS)
+	}
+	if len(r.AToB.Triangles) != len(a.Triangles) || len(r.BToA.Triangles) != len(b.Triangles) {
+		t.Fatalf("unexpected number of triangle deviations %d, %d", len(r.AToB.Triangles), len(r.BToA.Triangles))
+	}
+	for i, tr := range a.Triangles {
+		want := 0.1
+		switch {
+		case tr.Normal[2] > 0.5: // top of a is inside b
+			want = -0.1
+		case tr.Normal[2] > -0.5: // sides
+			continue
+		}
+		if !almostEqual64(r.AToB.Triangles[i], want, 1e-6) {
+			t.Errorf("triangle %d: expected deviation %g, got %g", i, want, r.AToB.Triangles[i])
+		}
+	}
+
+	r.AToB.Colorize(a, 0.1)
+	for i, tr := range a.Triangles {
+		var want uint16
+		switch {
+		case tr.Normal[2] > 0.5:
+			want = 1<<15 | 31 // blue
+		case tr.Normal[2] < -0.5:
+			want = 1<<15 | 31<<10 // red
+		default:
+			continue
+		}
+		if tr.Attributes != want {
+			t.Errorf("triangle %d: expected colour %#04x, got %#04x", i, want, tr.Attributes)
+		}
+	}
+}
+
+func TestCompareEqual(t *testing.T) {
+	a := makeTestTorus(3, 1, 24, 12)
+	r := Compare(a, makeTestTorus(3, 1, 24, 12), CompareOptions{})
+	if r.Hausdorff > 1e-9 || r.AToB.Mean > 1e-9 || r.BToA.RMS > 1e-9 {
+		t.Errorf("expected no deviation, got %g, %g, %g", r.Hausdorff, r.AToB.Mean, r.BToA.RMS)
+	}
+	r.AToB.Colorize(a, 0.1)
+	for i, tr := range a.Triangles {
+		if tr.Attributes != 1<<15|31<<5 {
+			t.Errorf("triangle %d: expected green, got %#04x", i, tr.Attributes)
+		}
+	}
+}
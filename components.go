@@ -0,0 +1,179 @@
+package stl
+
+// This file contains functions to find connected components of a solid
+
+// unionFind is a disjoint-set forest over the indices of the slice.
+type unionFind []int
+
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+// find returns the representative of the set containing i.
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+// union merges the sets containing i and j.
+func (u unionFind) union(i, j int) {
+	ri, rj := u.find(i), u.find(j)
+	if ri < rj {
+		u[rj] = ri
+	} else if rj < ri {
+		u[ri] = rj
+	}
+}
+
+// Connectivity determines which triangles belong to the same component.
+type Connectivity int
+
+const (
+	// EdgeConnected triangles are in the same component if they share an
+	// edge, or are connected by a chain of triangles sharing edges.
+	EdgeConnected Connectivity = iota
+
+	// VertexConnected triangles are in the same component if they share a
+	// vertex, so shells only touching in a single point are not separated.
+	VertexConnected
+)
+
+// ComponentInfo describes a connected component of a solid, as returned by
+// Solid.ComponentInfos.
+type ComponentInfo struct {
+	// Triangles contains the ascending indices of the triangles belonging
+	// to the component.
+	Triangles []int
+
+	// Volume is the signed volume enclosed by the component, see Solid.Volume.
+	Volume float64
+
+	// Measure contains the bounding box of the component.
+	Measure SolidMeasure
+}
+
+// Components splits s into its connected components, typically the
+// separate shells of a model. Vertices are only considered equal if they
+// are exactly equal. The components are returned in the order of their
+// first triangle, and keep the order and attributes of their triangles,
+// as well as the name, binary header and format of s.
+func (s *Solid) Components(connectivity Connectivity) []*Solid {
+	return s.splitByLabels(s.componentLabels(connectivity))
+}
+
+// ComponentInfos returns the triangle indices, volume, and bounding box of
+// every component returned by Components, in the same order.
+func (s *Solid) ComponentInfos(connectivity Connectivity) []ComponentInfo {
+	labels, count := s.componentLabels(connectivity)
+	infos := make([]ComponentInfo, count)
+	for i, label := range labels {
+		infos[label].Triangles = append(infos[label].Triangles, i)
+	}
+	for c, component := range s.splitByLabels(labels, count) {
+		infos[c].Volume = component.Volume()
+		infos[c].Measure = component.Measure()
+	}
+	return infos
+}
+
+// splitByLabels returns count solids, each one containing the triangles of s
+// with the same label, and the name, binary header and format of s.
+func (s *Solid) splitByLabels(labels []int, count int) []*Solid {
+	components := make([]*Solid, count)
+	for c := range components {
+		components[c] = &Solid{
+			BinaryHeader: s.BinaryHeader,
+			Name:         s.Name,
+			IsAscii:      s.IsAscii,
+		}
+	}
+	for i, label := range labels {
+		components[label].Triangles = append(components[label].Triangles, s.Triangles[i])
+	}
+	return components
+}
+
+// componentLabels labels every triangle of s with the index of its connected
+// component, see faceComponents.
+func (s *Solid) componentLabels(connectivity Connectivity) (labels []int, count int) {
+	m := NewIndexedMesh(s, 0)
+	if connectivity == VertexConnected {
+		return vertexFaceComponents(m)
+	}
+	return faceComponents(m)
+}
+
+// faceComponents labels every face of m with the index of its connected
+// component, counting from 0 in the order of the first face of each component.
+// Faces are connected if they share an edge. Returns the labels and the
+// number of components.
+func faceComponents(m *IndexedMesh) (labels []int, count int) {
+	u := newUnionFind(len(m.Faces))
+	edgeFace := make(map[[2]uint32]int, 3*len(m.Faces)/2)
+	for f, face := range m.Faces {
+		for v := 0; v < 3; v++ {
+			key := undirectedEdge(face[v], face[(v+1)%3])
+			if key[0] == key[1] {
+				continue
+			}
+			if other, found := edgeFace[key]; found {
+				u.union(f, other)
+			} else {
+				edgeFace[key] = f
+			}
+		}
+	}
+	return u.labels()
+}
+
+// vertexFaceComponents labels the faces of m like faceComponents, but faces
+// are already connected if they share a vertex.
+func vertexFaceComponents(m *IndexedMesh) (labels []int, count int) {
+	u := newUnionFind(len(m.Faces))
+	vertexFace := make([]int, len(m.Vertices))
+	for v := range vertexFace {
+		vertexFace[v] = -1
+	}
+	for f, face := range m.Faces {
+		for _, v := range face {
+			if vertexFace[v] >= 0 {
+				u.union(f, vertexFace[v])
+			} else {
+				vertexFace[v] = f
+			}
+		}
+	}
+	return u.labels()
+}
+
+// labels numbers the sets of u from 0 in the order of their first element.
+func (u unionFind) labels() (labels []int, count int) {
+	labels = make([]int, len(u))
+	rootLabel := make(map[int]int)
+	for i := range u {
+		root := u.find(i)
+		label, found := rootLabel[root]
+		if !found {
+			label = count
+			rootLabel[root] = label
+			count++
+		}
+		labels[i] = label
+	}
+	return labels, count
+}
+
+// undirectedEdge returns the edge between vertices a and b with the smaller index first.
+func undirectedEdge(a, b uint32) [2]uint32 {
+	if a < b {
+		return [2]uint32{a, b}
+	}
+	return [2]uint32{b, a}
+}
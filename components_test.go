@@ -0,0 +1,58 @@
+package stl
+
+// Tests for connected components
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestComponents(t *testing.T) {
+	// two cubes touching in a corner, and a tetrahedron far away
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = append(s.Triangles, makeTestCube(Vec3{1, 1, 1}, 2).Triangles...)
+	tetrahedron := makeTestSolid()
+	tetrahedron.Translate(Vec3{10, 0, 0})
+	s.Triangles = append(s.Triangles, tetrahedron.Triangles...)
+	s.Name = "parts"
+	for i := range s.Triangles {
+		s.Triangles[i].Attributes = uint16(i)
+	}
+
+	components := s.Components(EdgeConnected)
+	if len(components) != 3 {
+		t.Fatalf("expected 3 edge connected components, got %d", len(components))
+	}
+	for c, n := range []int{12, 12, 4} {
+		if len(components[c].Triangles) != n {
+			t.Errorf("component %d: expected %d triangles, got %d", c, n, len(components[c].Triangles))
+		}
+		if components[c].Name != "parts" {
+			t.Errorf("component %d: name %q not kept", c, components[c].Name)
+		}
+	}
+	if a := components[1].Triangles[0].Attributes; a != 12 {
+		t.Errorf("expected attributes 12 of first triangle of second cube, got %d", a)
+	}
+
+	if components := s.Components(VertexConnected); len(components) != 2 || len(components[0].Triangles) != 24 {
+		t.Errorf("expected 2 vertex connected components, first with 24 triangles")
+	}
+
+	infos := s.ComponentInfos(EdgeConnected)
+	if len(infos) != 3 {
+		t.Fatalf("expected 3 component infos, got %d", len(infos))
+	}
+	if !reflect.DeepEqual(infos[2].Triangles, []int{24, 25, 26, 27}) {
+		t.Errorf("unexpected triangles of the tetrahedron: %v", infos[2].Triangles)
+	}
+	for c, v := range []float64{1, 8, 1.0 / 6} {
+		if !almostEqual64(infos[c].Volume, v, 1e-6) {
+			t.Errorf("component %d: expected volume %g, got %g", c, v, infos[c].Volume)
+		}
+	}
+	expected := SolidMeasure{Min: Vec3{1, 1, 1}, Max: Vec3{3, 3, 3}, Len: Vec3{2, 2, 2}}
+	if infos[1].Measure != expected {
+		t.Errorf("expected bounding box %v of second cube, got %v", expected, infos[1].Measure)
+	}
+}
@@ -0,0 +1,568 @@
+package stl
+
+// This file contains boolean operations on closed solids, using BSP trees
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// NotClosedError is returned by the boolean operations if an operand is not
+// a closed solid, because Validate reports equal vertices or edge errors.
+type NotClosedError struct {
+	// Operand is 0 for the solid the method was called on, and 1 for its argument.
+	Operand int
+
+	// Errors contains the errors reported by Validate that prevent the
+	// operand from being closed.
+	Errors map[int]*TriangleErrors
+}
+
+func (e *NotClosedError) Error() string {
+	return fmt.Sprintf("operand %d is not a closed solid, %d triangles with errors", e.Operand, len(e.Errors))
+}
+
+// Union returns a new solid containing the volume of s and of other. Both
+// have to be closed solids, otherwise a *NotClosedError is returned. Their
+// orientation does not matter, the result always faces outwards. The result
+// has the name, binary header and format of s. Triangles keep the attributes
+// of the triangle they were cut from.
+//
+// Like all boolean operations, Union works in double precision, treating
+// points closer than a millionth of the model's size to a plane as lying
+// within the plane. T-junctions created by splitting triangles are removed,
+// and gaps left where the surfaces intersect at a flat angle are closed, so
+// the result of closed inputs is closed, too.
+func (s *Solid) Union(other *Solid) (*Solid, error) {
+	return s.boolean(other, func(a, b *csgNode) {
+		a.clipTo(b)
+		b.clipTo(a)
+		b.invert()
+		b.clipTo(a)
+		b.invert()
+		a.build(b.allPolygons())
+	})
+}
+
+// Difference returns a new solid containing the volume of s that is not
+// part of other, see Union.
+func (s *Solid) Difference(other *Solid) (*Solid, error) {
+	return s.boolean(other, func(a, b *csgNode) {
+		a.invert()
+		a.clipTo(b)
+		b.clipTo(a)
+		b.invert()
+		b.clipTo(a)
+		b.invert()
+		a.build(b.allPolygons())
+		a.invert()
+	})
+}
+
+// Intersection returns a new solid containing the volume that is part of
+// both s and other, see Union.
+func (s *Solid) Intersection(other *Solid) (*Solid, error) {
+	return s.boolean(other, func(a, b *csgNode) {
+		a.invert()
+		b.clipTo(a)
+		b.invert()
+		a.clipTo(b)
+		b.clipTo(a)
+		a.build(b.allPolygons())
+		a.invert()
+	})
+}
+
+// boolean checks the operands, builds their BSP trees, and converts the
+// polygons of a after calling op to the resulting solid.
+func (s *Solid) boolean(other *Solid, op func(a, b *csgNode)) (*Solid, error) {
+	for i, operand := range [2]*Solid{s, other} {
+		if errors := operand.closedErrors(); len(errors) > 0 {
+			return nil, &NotClosedError{Operand: i, Errors: errors}
+		}
+	}
+
+	var scale float64
+	for _, operand := range [2]*Solid{s, other} {
+		for i := range operand.Triangles {
+			for _, v := range operand.Triangles[i].Vertices {
+				for d := 0; d < 3; d++ {
+					scale = math.Max(scale, math.Abs(float64(v[d])))
+				}
+			}
+		}
+	}
+	eps := 1e-6 * scale
+	if eps == 0 {
+		eps = 1e-6
+	}
+
+	a := newCSGNode(s.csgPolygons(eps), eps)
+	b := newCSGNode(other.csgPolygons(eps), eps)
+	op(a, b)
+
+	result := polygonsToSolid(a.allPolygons(), eps)
+	result.Name = s.Name
+	result.BinaryHeader = s.BinaryHeader
+	result.IsAscii = s.IsAscii
+	return result, nil
+}
+
+// closedErrors returns the errors reported by Validate, that prevent s from
+// being closed, i.e. all but mismatching normal vectors.
+func (s *Solid) closedErrors() map[int]*TriangleErrors {
+	errors := make(map[int]*TriangleErrors)
+	for i, te := range s.Validate() {
+		if te.HasEqualVertices || te.EdgeErrors[0] != nil || te.EdgeErrors[1] != nil || te.EdgeErrors[2] != nil {
+			errors[i] = te
+		}
+	}
+	return errors
+}
+
+// csgPolygons converts the triangles of s into outward facing polygons,
+// skipping triangles that are thinner than eps, as their plane is not
+// well defined.
+func (s *Solid) csgPolygons(eps float64) []csgPolygon {
+	flip := NewIndexedMesh(s, 0).orientation()
+	polygons := make([]csgPolygon, 0, len(s.Triangles))
+	for i := range s.Triangles {
+		t := &s.Triangles[i]
+		vertices := []vec64{toVec64(t.Vertices[0]), toVec64(t.Vertices[1]), toVec64(t.Vertices[2])}
+		if flip[i] {
+			vertices[1], vertices[2] = vertices[2], vertices[1]
+		}
+		n := vertices[1].sub(vertices[0]).cross(vertices[2].sub(vertices[0]))
+		longest := math.Max(vertices[1].sub(vertices[0]).len(),
+			math.Max(vertices[2].sub(vertices[1]).len(), vertices[0].sub(vertices[2]).len()))
+		if n.len() <= eps*longest {
+			continue
+		}
+		n = n.unit()
+		polygons = append(polygons, csgPolygon{
+			vertices:   vertices,
+			plane:      csgPlane{normal: n, w: n.dot(vertices[0])},
+			attributes: t.Attributes,
+		})
+	}
+	return polygons
+}
+
+// csgPlane is the plane of all points p with normal.dot(p) == w.
+type csgPlane struct {
+	normal vec64
+	w      float64
+}
+
+// csgPolygon is a convex polygon with the attributes of the triangle it
+// originates from.
+type csgPolygon struct {
+	vertices   []vec64
+	plane      csgPlane
+	attributes uint16
+}
+
+// flip reverses the orientation of p. The vertices are copied, as they may
+// be shared with polygons of another tree.
+func (p *csgPolygon) flip() {
+	vertices := make([]vec64, len(p.vertices))
+	for i, v := range p.vertices {
+		vertices[len(vertices)-1-i] = v
+	}
+	p.vertices = vertices
+	p.plane.normal = p.plane.normal.scale(-1)
+	p.plane.w = -p.plane.w
+}
+
+// Classification of points and polygons relative to a plane.
+const (
+	csgCoplanar = 0
+	csgFront    = 1
+	csgBack     = 2
+	csgSpanning = 3
+)
+
+// split sorts polygon p into the lists for polygons coplanar to the plane
+// facing in the same or the opposite direction, in front of, or behind it.
+// Polygons spanning the plane are split in two.
+func (pl *csgPlane) split(p csgPolygon, eps float64, coplanarFront, coplanarBack, front, back *[]csgPolygon) {
+	polygonType := 0
+	types := make([]int, len(p.vertices))
+	for i, v := range p.vertices {
+		t := pl.normal.dot(v) - pl.w
+		switch {
+		case t < -eps:
+			types[i] = csgBack
+		case t > eps:
+			types[i] = csgFront
+		}
+		polygonType |= types[i]
+	}
+
+	switch polygonType {
+	case csgCoplanar:
+		if pl.normal.dot(p.plane.normal) > 0 {
+			*coplanarFront = append(*coplanarFront, p)
+		} else {
+			*coplanarBack = append(*coplanarBack, p)
+		}
+	case csgFront:
+		*front = append(*front, p)
+	case csgBack:
+		*back = append(*back, p)
+	case csgSpanning:
+		var f, b []vec64
+		for i, vi := range p.vertices {
+			j := (i + 1) % len(p.vertices)
+			ti, tj := types[i], types[j]
+			vj := p.vertices[j]
+			if ti != csgBack {
+				f = append(f, vi)
+			}
+			if ti != csgFront {
+				b = append(b, vi)
+			}
+			if ti|tj == csgSpanning {
+				t := (pl.w - pl.normal.dot(vi)) / pl.normal.dot(vj.sub(vi))
+				v := vi.add(vj.sub(vi).scale(t))
+				f = append(f, v)
+				b = append(b, v)
+			}
+		}
+		if len(f) >= 3 {
+			*front = append(*front, csgPolygon{vertices: f, plane: p.plane, attributes: p.attributes})
+		}
+		if len(b) >= 3 {
+			*back = append(*back, csgPolygon{vertices: b, plane: p.plane, attributes: p.attributes})
+		}
+	}
+}
+
+// csgNode is a node of a BSP tree. The polygons of the node lie in its
+// plane, the front subtree contains the polygons in front of it, the back
+// subtree those behind it.
+type csgNode struct {
+	plane       csgPlane
+	hasPlane    bool
+	front, back *csgNode
+	polygons    []csgPolygon
+	eps         float64
+}
+
+func newCSGNode(polygons []csgPolygon, eps float64) *csgNode {
+	n := &csgNode{eps: eps}
+	n.build(polygons)
+	return n
+}
+
+// invert converts the solid represented by the tree into its complement.
+func (n *csgNode) invert() {
+	for i := range n.polygons {
+		n.polygons[i].flip()
+	}
+	n.plane.normal = n.plane.normal.scale(-1)
+	n.plane.w = -n.plane.w
+	if n.front != nil {
+		n.front.invert()
+	}
+	if n.back != nil {
+		n.back.invert()
+	}
+	n.front, n.back = n.back, n.front
+}
+
+// clipPolygons removes all parts of polygons that are inside the solid
+// represented by the tree.
+func (n *csgNode) clipPolygons(polygons []csgPolygon) []csgPolygon {
+	if !n.hasPlane {
+		return append([]csgPolygon(nil), polygons...)
+	}
+	var front, back []csgPolygon
+	for _, p := range polygons {
+		n.plane.split(p, n.eps, &front, &back, &front, &back)
+	}
+	if n.front != nil {
+		front = n.front.clipPolygons(front)
+	}
+	if n.back != nil {
+		back = n.back.clipPolygons(back)
+	} else {
+		back = nil
+	}
+	return append(front, back...)
+}
+
+// clipTo removes all parts of the polygons in n that are inside the solid
+// represented by other.
+func (n *csgNode) clipTo(other *csgNode) {
+	n.polygons = other.clipPolygons(n.polygons)
+	if n.front != nil {
+		n.front.clipTo(other)
+	}
+	if n.back != nil {
+		n.back.clipTo(other)
+	}
+}
+
+// allPolygons returns the polygons of all nodes of the tree.
+func (n *csgNode) allPolygons() []csgPolygon {
+	polygons := append([]csgPolygon(nil), n.polygons...)
+	if n.front != nil {
+		polygons = append(polygons, n.front.allPolygons()...)
+	}
+	if n.back != nil {
+		polygons = append(polygons, n.back.allPolygons()...)
+	}
+	return polygons
+}
+
+// build adds polygons to the tree, splitting them where necessary. The
+// polygon in the middle of the list becomes the plane of new nodes, which
+// avoids degenerated trees for polygons ordered by position.
+func (n *csgNode) build(polygons []csgPolygon) {
+	if len(polygons) == 0 {
+		return
+	}
+	if !n.hasPlane {
+		n.plane = polygons[len(polygons)/2].plane
+		n.hasPlane = true
+	}
+	var front, back []csgPolygon
+	for _, p := range polygons {
+		n.plane.split(p, n.eps, &n.polygons, &n.polygons, &front, &back)
+	}
+	if len(front) > 0 {
+		if n.front == nil {
+			n.front = &csgNode{eps: n.eps}
+		}
+		n.front.build(front)
+	}
+	if len(back) > 0 {
+		if n.back == nil {
+			n.back = &csgNode{eps: n.eps}
+		}
+		n.back.build(back)
+	}
+}
+
+// polygonsToSolid welds the vertices of polygons that are closer than eps,
+// inserts vertices lying on the edges of other polygons to remove T-junctions,
+// triangulates the polygons, and closes the remaining gaps, see closeGaps.
+func polygonsToSolid(polygons []csgPolygon, eps float64) *Solid {
+	w := newVertexWelder(float32(eps), 3*len(polygons))
+	faces := make([][]uint32, 0, len(polygons))
+	var edgeLengthSum float64
+	edgeCount := 0
+	for _, p := range polygons {
+		var face []uint32
+		for _, v := range p.vertices {
+			idx := w.index(v.vec3())
+			if len(face) == 0 || face[len(face)-1] != idx {
+				face = append(face, idx)
+			}
+		}
+		for len(face) > 1 && face[0] == face[len(face)-1] {
+			face = face[:len(face)-1]
+		}
+		if len(face) < 3 {
+			face = nil
+		}
+		for i := range face {
+			edgeLengthSum += float64(w.vertices[face[i]].Diff(w.vertices[face[(i+1)%len(face)]]).len())
+			edgeCount++
+		}
+		faces = append(faces, face)
+	}
+
+	s := &Solid{}
+	if edgeCount == 0 {
+		return s
+	}
+	g := newVertexGrid(edgeLengthSum / float64(edgeCount))
+	vertices := make([]vec64, len(w.vertices))
+	for i, v := range w.vertices {
+		vertices[i] = toVec64(v)
+		g.insert(i, vertices[i])
+	}
+
+	for f, face := range faces {
+		if face == nil {
+			continue
+		}
+		var polygon []Vec3
+		for i := range face {
+			a, b := face[i], face[(i+1)%len(face)]
+			polygon = append(polygon, w.vertices[a])
+			for _, idx := range verticesOnEdge(g, vertices, int(a), int(b), eps) {
+				polygon = append(polygon, w.vertices[idx])
+			}
+		}
+		normal := polygons[f].plane.normal.vec3()
+		for _, vs := range triangulatePolygon(polygon) {
+			t := Triangle{Vertices: vs, Attributes: polygons[f].attributes}
+			if t.hasEqualVertices() {
+				continue
+			}
+			t.recalculateNormal()
+			if !t.Normal.isFinite() || t.Normal == vec3Zero {
+				t.Normal = normal
+			}
+			s.Triangles = append(s.Triangles, t)
+		}
+	}
+	s.closeGaps(eps)
+	return s
+}
+
+// closeGaps repairs the triangles of the result of a boolean operation,
+// whose polygons only fit together up to eps. Where the intersection of the
+// operands is almost tangent to their surfaces, split points lie farther
+// apart, and thin fragments can be clipped away inconsistently. So edges
+// shared by more than two triangles are collapsed if they are short,
+// coincident triangles are removed, in pairs if they face opposite
+// directions, and the remaining holes are filled.
+func (s *Solid) closeGaps(eps float64) {
+	m := NewIndexedMesh(s, 0)
+	if m.collapseNonManifoldEdges(10*eps) > 0 {
+		keep := make([]bool, len(m.Faces))
+		for f, face := range m.Faces {
+			keep[f] = face[0] != face[1] && face[0] != face[2] && face[1] != face[2]
+		}
+		m.filterFaces(keep)
+		s.Triangles = m.Solid().Triangles
+		for i := range s.Triangles {
+			t := &s.Triangles[i]
+			normal := t.Normal
+			t.recalculateNormal()
+			if !t.Normal.isFinite() || t.Normal == vec3Zero {
+				t.Normal = normal
+			}
+		}
+	}
+	s.removeCoincident(true, true, 0)
+	s.FillHoles(0)
+}
+
+// collapseNonManifoldEdges merges the vertices of every edge that is used by
+// more than two faces, and at most maxLength long. The merged vertex is the
+// one with the smaller index. Returns the number of collapsed edges.
+func (m *IndexedMesh) collapseNonManifoldEdges(maxLength float64) int {
+	uses := make(map[[2]uint32]int, 3*len(m.Faces)/2)
+	for _, face := range m.Faces {
+		for v := 0; v < 3; v++ {
+			uses[undirectedEdge(face[v], face[(v+1)%3])]++
+		}
+	}
+	u := newUnionFind(len(m.Vertices))
+	collapsed := 0
+	for edge, count := range uses {
+		if count > 2 && toVec64(m.Vertices[edge[0]]).sub(toVec64(m.Vertices[edge[1]])).len() <= maxLength {
+			u.union(int(edge[0]), int(edge[1]))
+			collapsed++
+		}
+	}
+	if collapsed > 0 {
+		for f := range m.Faces {
+			for v := 0; v < 3; v++ {
+				m.Faces[f][v] = uint32(u.find(int(m.Faces[f][v])))
+			}
+		}
+	}
+	return collapsed
+}
+
+// verticesOnEdge returns the indices of the vertices stored in g lying
+// strictly between the vertices a and b, within distance eps of the edge
+// between them, ordered from a to b.
+func verticesOnEdge(g *vertexGrid, vertices []vec64, a, b int, eps float64) []int {
+	pa, pb := vertices[a], vertices[b]
+	ab := pb.sub(pa)
+	lengthSquared := ab.dot(ab)
+	if lengthSquared == 0 {
+		return nil
+	}
+	box := box64{pa, pa}
+	box.extend(pb)
+	box.min = box.min.sub(vec64{eps, eps, eps})
+	box.max = box.max.add(vec64{eps, eps, eps})
+
+	var found []int
+	var params []float64
+	g.forCells(box, func(cell [3]int) bool {
+		for _, i := range g.cells[cell] {
+			if i == a || i == b {
+				continue
+			}
+			ap := vertices[i].sub(pa)
+			t := ap.dot(ab) / lengthSquared
+			if t <= 0 || t >= 1 {
+				continue
+			}
+			if d := ap.sub(ab.scale(t)); d.dot(d) > eps*eps {
+				continue
+			}
+			found = append(found, i)
+			params = append(params, t)
+		}
+		return true
+	})
+	sort.Sort(byParam{found, params})
+	return found
+}
+
+// byParam sorts indices by their parameter along an edge.
+type byParam struct {
+	indices []int
+	params  []float64
+}
+
+func (b byParam) Len() int           { return len(b.indices) }
+func (b byParam) Less(i, j int) bool { return b.params[i] < b.params[j] }
+func (b byParam) Swap(i, j int) {
+	b.indices[i], b.indices[j] = b.indices[j], b.indices[i]
+	b.params[i], b.params[j] = b.params[j], b.params[i]
+}
+
+// vertexGrid is a uniform grid storing the indices of the vertices in every
+// cell.
+type vertexGrid struct {
+	cellSize float64
+	cells    map[[3]int][]int
+}
+
+func newVertexGrid(cellSize float64) *vertexGrid {
+	if !(cellSize > 0) {
+		cellSize = 1
+	}
+	return &vertexGrid{cellSize: cellSize, cells: make(map[[3]int][]int)}
+}
+
+// cellOf returns the cell containing p.
+func (g *vertexGrid) cellOf(p vec64) [3]int {
+	return [3]int{
+		int(math.Floor(p[0] / g.cellSize)),
+		int(math.Floor(p[1] / g.cellSize)),
+		int(math.Floor(p[2] / g.cellSize)),
+	}
+}
+
+// forCells calls fn for every cell overlapping b, until fn returns false.
+func (g *vertexGrid) forCells(b box64, fn func(cell [3]int) bool) {
+	lo, hi := g.cellOf(b.min), g.cellOf(b.max)
+	for x := lo[0]; x <= hi[0]; x++ {
+		for y := lo[1]; y <= hi[1]; y++ {
+			for z := lo[2]; z <= hi[2]; z++ {
+				if !fn([3]int{x, y, z}) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// insert adds vertex i at p to its cell.
+func (g *vertexGrid) insert(i int, p vec64) {
+	cell := g.cellOf(p)
+	g.cells[cell] = append(g.cells[cell], i)
+}
@@ -0,0 +1,188 @@
+package stl
+
+// Tests for boolean operations
+
+import (
+	"testing"
+)
+
+func TestBooleanOperations(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 2)
+	b := makeTestCube(Vec3{1, 1, 1}, 2)
+	for i := range b.Triangles {
+		b.Triangles[i].Attributes = 7
+	}
+
+	for _, test := range []struct {
+		name   string
+		op     func(*Solid) (*Solid, error)
+		volume float64
+	}{
+		{"union", a.Union, 15},
+		{"difference", a.Difference, 7},
+		{"intersection", a.Intersection, 1},
+	} {
+		result, err := test.op(b)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if errors := result.Validate(); len(errors) != 0 {
+			t.Errorf("%s: result is not valid: %v", test.name, errors)
+		}
+		if v := result.Volume(); !almostEqual64(v, test.volume, 1e-5) {
+			t.Errorf("%s: expected volume %g, got %g", test.name, test.volume, v)
+		}
+		if result.Name != a.Name || result.IsAscii != a.IsAscii {
+			t.Errorf("%s: name and format of first operand not kept", test.name)
+		}
+		tagged := false
+		for _, tr := range result.Triangles {
+			if tr.Attributes == 7 {
+				tagged = true
+			}
+		}
+		if !tagged {
+			t.Errorf("%s: attributes of second operand are lost", test.name)
+		}
+	}
+}
+
+func TestBooleanCoplanarAndInsideOut(t *testing.T) {
+	// cubes sharing a face, the second one inside-out
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	b := makeTestCube(Vec3{1, 0, 0}, 1)
+	for i := range b.Triangles {
+		b.Triangles[i].Vertices[1], b.Triangles[i].Vertices[2] = b.Triangles[i].Vertices[2], b.Triangles[i].Vertices[1]
+		b.Triangles[i].recalculateNormal()
+	}
+	union, err := a.Union(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := union.Validate(); len(errors) != 0 {
+		t.Errorf("union is not valid: %v", errors)
+	}
+	if v := union.Volume(); !almostEqual64(v, 2, 1e-5) {
+		t.Errorf("expected volume 2, got %g", v)
+	}
+
+	// a torus with a bar through it
+	torus := makeTestTorus(2, 0.5, 24, 12)
+	bar := makeTestCube(Vec3{0, 0, 0}, 1)
+	bar.Stretch(Vec3{6, 0.5, 0.5})
+	bar.Translate(Vec3{-3, -0.25, -0.25})
+	difference, err := torus.Difference(bar)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := difference.Validate(); len(errors) != 0 {
+		t.Errorf("difference is not valid: %d errors", len(errors))
+	}
+	if v := difference.Volume(); v <= 0 || v >= 0.98*torus.Volume() {
+		t.Errorf("unexpected volume %g of difference, torus has %g", v, torus.Volume())
+	}
+}
+
+func TestBooleanSliver(t *testing.T) {
+	// the top face of a cube is split along its diagonal by a vertex that
+	// rounding moved off it, leaving a sliver facing inwards
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	c0, c1, c2, c3 := Vec3{0, 0, 1}, Vec3{1, 0, 1}, Vec3{1, 1, 1}, Vec3{0, 1, 1}
+	p := Vec3{0.5, 0.5 + 6e-8, 1}
+	tri := func(a, b, c Vec3) Triangle {
+		t := Triangle{Vertices: [3]Vec3{a, b, c}}
+		t.recalculateNormal()
+		return t
+	}
+	triangles := append([]Triangle(nil), a.Triangles[:7]...)
+	// in the middle of the list, so that its plane is used first
+	triangles = append(triangles, tri(c0, p, c2))
+	triangles = append(triangles, a.Triangles[7:10]...)
+	a.Triangles = append(triangles, tri(c0, c1, p), tri(p, c1, c2), tri(c0, c2, c3))
+	if errors := a.closedErrors(); len(errors) != 0 {
+		t.Fatalf("test solid is not closed: %v", errors)
+	}
+
+	b := makeTestCube(Vec3{0.25, 0.25, 0.5}, 1)
+	for _, test := range []struct {
+		name   string
+		op     func(*Solid) (*Solid, error)
+		volume float64
+	}{
+		{"union", a.Union, 1.71875},
+		{"difference", a.Difference, 0.71875},
+		{"intersection", a.Intersection, 0.28125},
+	} {
+		result, err := test.op(b)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if errors := result.Validate(); len(errors) != 0 {
+			t.Errorf("%s: result is not valid: %d errors", test.name, len(errors))
+		}
+		if v := result.Volume(); !almostEqual64(v, test.volume, 1e-5) {
+			t.Errorf("%s: expected volume %g, got %g", test.name, test.volume, v)
+		}
+	}
+}
+
+func TestBooleanSpheres(t *testing.T) {
+	// split points on edges crossing the curved surface at a flat angle
+	// lie close to each other, but not within the tolerance
+	a := makeTestSphere(Vec3{0, 0, 0}, 10, 48, 24)
+	b := makeTestSphere(Vec3{7, 3, 1}, 10, 48, 24)
+	results := make(map[string]*Solid)
+	for _, test := range []struct {
+		name string
+		op   func(*Solid) (*Solid, error)
+	}{
+		{"union", a.Union},
+		{"difference", a.Difference},
+		{"intersection", a.Intersection},
+	} {
+		result, err := test.op(b)
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", test.name, err)
+		}
+		if errors := result.Validate(); len(errors) != 0 {
+			t.Errorf("%s: result is not valid: %d errors", test.name, len(errors))
+		}
+		if analysis := result.Analyze(); !analysis.IsManifold {
+			t.Errorf("%s: result is not manifold", test.name)
+		}
+		results[test.name] = result
+	}
+	if v := results["difference"].Volume() + results["intersection"].Volume(); !almostEqual64(v, a.Volume(), 1e-3) {
+		t.Errorf("difference and intersection have volume %g, expected %g", v, a.Volume())
+	}
+
+	// the results can be used as operands again
+	intersection := results["intersection"]
+	c := makeTestSphere(Vec3{2, -4, 3}, 6, 32, 16)
+	union, err := intersection.Union(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errors := union.Validate(); len(errors) != 0 {
+		t.Errorf("chained union is not valid: %d errors", len(errors))
+	}
+	if v := union.Volume(); v <= intersection.Volume() || v >= intersection.Volume()+c.Volume() {
+		t.Errorf("unexpected chained union volume %g, operands have %g and %g", v, intersection.Volume(), c.Volume())
+	}
+}
+
+func TestBooleanNotClosed(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	b := makeTestCube(Vec3{0.5, 0.5, 0.5}, 1)
+	b.Triangles = b.Triangles[1:]
+	_, err := a.Union(b)
+	notClosed, ok := err.(*NotClosedError)
+	if !ok {
+		t.Fatalf("expected NotClosedError, got %v", err)
+	}
+	if notClosed.Operand != 1 || len(notClosed.Errors) == 0 {
+		t.Errorf("unexpected error %+v", notClosed)
+	}
+}
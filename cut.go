@@ -0,0 +1,301 @@
+package stl
+
+// This file contains cutting a solid into two closed parts by a plane
+
+import (
+	"math"
+	"sort"
+)
+
+// CutOptions control Solid.CutWithOptions.
+type CutOptions struct {
+	// PinPositions are the centres of alignment pin holes drilled into both
+	// parts from the cut plane. Points not in the plane are projected onto it.
+	PinPositions []Vec3
+
+	// PinDiameter is the diameter of the pin holes.
+	PinDiameter float64
+
+	// PinDepth is the depth of the pin holes in each of the two parts.
+	PinDepth float64
+
+	// PinSides is the number of sides of the polygon approximating the
+	// circular pin holes. The default 0 uses 16 sides.
+	PinSides int
+}
+
+// Cut splits s by the plane through planePoint orthogonal to planeNormal.
+// Triangles crossing the plane are split, and the cross-section is
+// triangulated, so a closed solid results in two closed parts. above
+// contains the part on the side planeNormal points to, below the other one.
+// Either part may have no triangles. Vertices lying exactly in the plane are
+// treated as lying above it, like in Slice. Triangles lying in the plane are
+// dropped, as the cross-section just above and just below the plane is used
+// as the cap of either part, so cutting along a face of s leaves one part
+// empty.
+//
+// Triangles that are not split keep their attributes, those of split
+// triangles are kept for all pieces, and the triangles of the cross-section
+// have attributes 0. Both parts have the name, binary header and format of s.
+func (s *Solid) Cut(planePoint, planeNormal Vec3) (above, below *Solid) {
+	n := toVec64(planeNormal).unit()
+	h := n.dot(toVec64(planePoint))
+	above, below = s.emptyCopy(), s.emptyCopy()
+
+	for i := range s.Triangles {
+		t := &s.Triangles[i]
+		var isAbove [3]bool
+		aboveCount, inPlaneCount := 0, 0
+		for v := 0; v < 3; v++ {
+			d := n.dot(toVec64(t.Vertices[v]))
+			isAbove[v] = d >= h
+			if isAbove[v] {
+				aboveCount++
+			}
+			if d == h {
+				inPlaneCount++
+			}
+		}
+		if inPlaneCount == 3 {
+			continue
+		}
+		switch aboveCount {
+		case 3:
+			above.Triangles = append(above.Triangles, *t)
+			continue
+		case 0:
+			below.Triangles = append(below.Triangles, *t)
+			continue
+		}
+
+		lone := 0
+		for v := 0; v < 3; v++ {
+			if isAbove[v] == (aboveCount == 1) {
+				lone = v
+			}
+		}
+		a, b, c := t.Vertices[lone], t.Vertices[(lone+1)%3], t.Vertices[(lone+2)%3]
+		pab := edgePlaneIntersection(canonicalEdge(a, b), n, h).vec3()
+		pca := edgePlaneIntersection(canonicalEdge(c, a), n, h).vec3()
+		lonePart, otherPart := above, below
+		if aboveCount == 2 {
+			lonePart, otherPart = below, above
+		}
+		lonePart.appendNonDegenerate(t.Attributes, a, pab, pca)
+		otherPart.appendNonDegenerate(t.Attributes, pab, b, c)
+		otherPart.appendNonDegenerate(t.Attributes, pab, c, pca)
+	}
+
+	all := make([]int, len(s.Triangles))
+	for i := range all {
+		all[i] = i
+	}
+	layer := s.sliceLayer(n, h, all)
+	for _, t := range layer.capTriangles(n) {
+		below.appendNonDegenerate(0, t[0], t[1], t[2])
+	}
+	// slicing with the opposite normal treats vertices in the plane as
+	// below it, giving the cross-section just above the plane, facing down
+	down := n.scale(-1)
+	layer = s.sliceLayer(down, -h, all)
+	for _, t := range layer.capTriangles(down) {
+		above.appendNonDegenerate(0, t[0], t[1], t[2])
+	}
+	return above, below
+}
+
+// CutWithOptions cuts s like Cut, and then drills alignment pin holes into
+// both parts as described by options. Drilling uses Difference, so it returns
+// a *NotClosedError if a part is not closed.
+func (s *Solid) CutWithOptions(planePoint, planeNormal Vec3, options CutOptions) (above, below *Solid, err error) {
+	above, below = s.Cut(planePoint, planeNormal)
+	if len(options.PinPositions) == 0 {
+		return above, below, nil
+	}
+	n := toVec64(planeNormal).unit()
+	h := n.dot(toVec64(planePoint))
+	for _, p := range options.PinPositions {
+		q := toVec64(p)
+		centre := q.sub(n.scale(n.dot(q) - h))
+		pin := makePrism(centre, n, options.PinDiameter/2, options.PinDepth, options.PinSides)
+		if above, err = above.Difference(pin); err != nil {
+			return nil, nil, err
+		}
+		if below, err = below.Difference(pin); err != nil {
+			return nil, nil, err
+		}
+	}
+	return above, below, nil
+}
+
+// emptyCopy returns a solid without triangles, with the name, binary
+// header and format of s.
+func (s *Solid) emptyCopy() *Solid {
+	return &Solid{BinaryHeader: s.BinaryHeader, Name: s.Name, IsAscii: s.IsAscii}
+}
+
+// appendNonDegenerate appends the triangle a, b, c with a calculated normal,
+// unless it has equal vertices.
+func (s *Solid) appendNonDegenerate(attributes uint16, a, b, c Vec3) {
+	t := Triangle{Vertices: [3]Vec3{a, b, c}, Attributes: attributes}
+	if t.hasEqualVertices() {
+		return
+	}
+	t.recalculateNormal()
+	s.Triangles = append(s.Triangles, t)
+}
+
+// makePrism returns a closed prism with a regular polygon of the given number
+// of sides and circumradius as base, centred at centre, extending depth into
+// both directions of the unit vector axis.
+func makePrism(centre, axis vec64, radius, depth float64, sides int) *Solid {
+	if sides < 3 {
+		sides = 16
+	}
+	u, v := planeBasis(axis)
+	top, bottom := centre.add(axis.scale(depth)), centre.sub(axis.scale(depth))
+	ring := func(base vec64, i int) Vec3 {
+		angle := TwoPi * float64(i%sides) / float64(sides)
+		return base.add(u.scale(radius * math.Cos(angle))).add(v.scale(radius * math.Sin(angle))).vec3()
+	}
+	s := &Solid{}
+	for i := 0; i < sides; i++ {
+		s.appendNonDegenerate(0, top.vec3(), ring(top, i), ring(top, i+1))
+		s.appendNonDegenerate(0, bottom.vec3(), ring(bottom, i+1), ring(bottom, i))
+		s.appendNonDegenerate(0, ring(bottom, i), ring(bottom, i+1), ring(top, i+1))
+		s.appendNonDegenerate(0, ring(bottom, i), ring(top, i+1), ring(top, i))
+	}
+	return s
+}
+
+// capTriangles triangulates the areas enclosed by the closed contours of
+// layer, whose plane has the unit normal n. The triangles are
+// counter-clockwise seen from the side n points to.
+func (layer *Layer) capTriangles(n vec64) [][3]Vec3 {
+	u, v := planeBasis(n)
+	project := func(points []Vec3) [][2]float64 {
+		projected := make([][2]float64, len(points))
+		for i, p := range points {
+			q := toVec64(p)
+			projected[i] = [2]float64{q.dot(u), q.dot(v)}
+		}
+		return projected
+	}
+
+	var triangles [][3]Vec3
+	for c, outer := range layer.Contours {
+		if outer.IsHole {
+			continue
+		}
+		points := append([]Vec3(nil), outer.Points...)
+		rings := [][][2]float64{project(outer.Points)}
+		for _, hole := range layer.Contours {
+			if hole.IsHole && hole.Parent == c {
+				points = append(points, hole.Points...)
+				rings = append(rings, project(hole.Points))
+			}
+		}
+		merged, polygon := bridgeHoles(rings)
+		for _, t := range earClipping(polygon) {
+			triangles = append(triangles, [3]Vec3{points[merged[t[0]]], points[merged[t[1]]], points[merged[t[2]]]})
+		}
+	}
+	return triangles
+}
+
+// bridgeHoles connects the clockwise holes rings[1:] to the counter-clockwise
+// outer ring rings[0] by pairs of bridge edges, resulting in a single polygon
+// that can be triangulated by earClipping. It returns for every point of the
+// polygon its index in the concatenation of all rings, and the polygon.
+func bridgeHoles(rings [][][2]float64) (indices []int, polygon [][2]float64) {
+	var points [][2]float64
+	starts := make([]int, len(rings))
+	for r, ring := range rings {
+		starts[r] = len(points)
+		points = append(points, ring...)
+	}
+	for i := range rings[0] {
+		indices = append(indices, i)
+	}
+
+	// holes are bridged from their vertex with the largest x, in
+	// descending order of it
+	holes := make([]int, 0, len(rings)-1)
+	rightmost := make([]int, len(rings))
+	for r := 1; r < len(rings); r++ {
+		holes = append(holes, r)
+		for i := range rings[r] {
+			if rings[r][i][0] > rings[r][rightmost[r]][0] {
+				rightmost[r] = i
+			}
+		}
+	}
+	sort.Slice(holes, func(a, b int) bool {
+		return rings[holes[a]][rightmost[holes[a]]][0] > rings[holes[b]][rightmost[holes[b]]][0]
+	})
+
+	bridged := make([]bool, len(rings))
+	bridged[0] = true
+	for _, r := range holes {
+		m := rings[r][rightmost[r]]
+		// try the vertices of the current polygon ordered by distance
+		candidates := make([]int, len(indices))
+		for k := range candidates {
+			candidates[k] = k
+		}
+		dist := func(k int) float64 {
+			p := points[indices[k]]
+			return (p[0]-m[0])*(p[0]-m[0]) + (p[1]-m[1])*(p[1]-m[1])
+		}
+		sort.Slice(candidates, func(a, b int) bool { return dist(candidates[a]) < dist(candidates[b]) })
+		best := candidates[0]
+		for _, k := range candidates {
+			if bridgeIsVisible(m, points[indices[k]], points, indices, rings, bridged) {
+				best = k
+				break
+			}
+		}
+
+		var hole []int
+		for i := range rings[r] {
+			hole = append(hole, starts[r]+(rightmost[r]+i)%len(rings[r]))
+		}
+		hole = append(hole, starts[r]+rightmost[r], indices[best])
+		rest := append([]int(nil), indices[best+1:]...)
+		indices = append(append(indices[:best+1], hole...), rest...)
+		bridged[r] = true
+	}
+
+	polygon = make([][2]float64, len(indices))
+	for i, idx := range indices {
+		polygon[i] = points[idx]
+	}
+	return indices, polygon
+}
+
+// bridgeIsVisible returns true if the segment from m to p does not cross any
+// edge of the current polygon, or of the holes not bridged yet.
+func bridgeIsVisible(m, p [2]float64, points [][2]float64, indices []int, rings [][][2]float64, bridged []bool) bool {
+	crosses := func(a, b [2]float64) bool {
+		if a == m || b == m || a == p || b == p {
+			return false
+		}
+		return segmentsIntersect2(m, p, a, b)
+	}
+	for i := range indices {
+		if crosses(points[indices[i]], points[indices[(i+1)%len(indices)]]) {
+			return false
+		}
+	}
+	for h := 1; h < len(rings); h++ {
+		if bridged[h] {
+			continue
+		}
+		for i := range rings[h] {
+			if crosses(rings[h][i], rings[h][(i+1)%len(rings[h])]) {
+				return false
+			}
+		}
+	}
+	return true
+}
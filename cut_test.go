@@ -0,0 +1,134 @@
+package stl
+
+// Tests for cutting solids
+
+import (
+	"testing"
+)
+
+func TestCutCube(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	for i := range s.Triangles {
+		s.Triangles[i].Attributes = 3
+	}
+	above, below := s.Cut(Vec3{0, 0, 0.25}, Vec3{0, 0, 2})
+	for name, part := range map[string]*Solid{"above": above, "below": below} {
+		if errors := part.Validate(); len(errors) != 0 {
+			t.Errorf("%s: not valid: %v", name, errors)
+		}
+		if part.Name != s.Name {
+			t.Errorf("%s: name not kept", name)
+		}
+	}
+	if v := above.Volume(); !almostEqual64(v, 0.75, 1e-6) {
+		t.Errorf("expected volume 0.75 above, got %g", v)
+	}
+	if v := below.Volume(); !almostEqual64(v, 0.25, 1e-6) {
+		t.Errorf("expected volume 0.25 below, got %g", v)
+	}
+	// the +z face is unchanged
+	found := false
+	for _, tr := range above.Triangles {
+		if tr == s.Triangles[10] {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("unchanged triangle with attributes not found above")
+	}
+
+	// cutting beside the solid
+	above, below = s.Cut(Vec3{0, 0, 2}, Vec3{0, 0, 1})
+	if len(above.Triangles) != 0 || len(below.Triangles) != 12 {
+		t.Errorf("expected everything below, got %d above and %d below", len(above.Triangles), len(below.Triangles))
+	}
+}
+
+func TestCutAlongFace(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 10)
+	above, below := s.Cut(Vec3{0, 0, 10}, Vec3{0, 0, 1})
+	if len(above.Triangles) != 0 {
+		t.Errorf("cut along the top face: expected nothing above, got %d triangles", len(above.Triangles))
+	}
+	if errors := below.Validate(); len(errors) != 0 {
+		t.Errorf("cut along the top face: below not valid: %v", errors)
+	}
+	if v := below.Volume(); !almostEqual64(v, 1000, 1e-6) {
+		t.Errorf("cut along the top face: expected volume 1000 below, got %g", v)
+	}
+
+	above, below = s.Cut(Vec3{0, 0, 0}, Vec3{0, 0, 1})
+	if len(below.Triangles) != 0 {
+		t.Errorf("cut along the bottom face: expected nothing below, got %d triangles", len(below.Triangles))
+	}
+	if errors := above.Validate(); len(errors) != 0 {
+		t.Errorf("cut along the bottom face: above not valid: %v", errors)
+	}
+	if v := above.Volume(); !almostEqual64(v, 1000, 1e-6) {
+		t.Errorf("cut along the bottom face: expected volume 1000 above, got %g", v)
+	}
+
+	// a step, whose upper face is partly covered by a smaller box
+	step := makeTestCube(Vec3{0, 0, 0}, 2)
+	top := makeTestCube(Vec3{0, 0, 2}, 1)
+	top.Stretch(Vec3{1, 2, 1})
+	stepped, err := step.Union(top)
+	if err != nil {
+		t.Fatal(err)
+	}
+	above, below = stepped.Cut(Vec3{0, 0, 2}, Vec3{0, 0, 1})
+	for name, part := range map[string]*Solid{"above": above, "below": below} {
+		if errors := part.Validate(); len(errors) != 0 {
+			t.Errorf("step: %s not valid: %v", name, errors)
+		}
+	}
+	if v := above.Volume(); !almostEqual64(v, 2, 1e-6) {
+		t.Errorf("step: expected volume 2 above, got %g", v)
+	}
+	if v := below.Volume(); !almostEqual64(v, 8, 1e-6) {
+		t.Errorf("step: expected volume 8 below, got %g", v)
+	}
+}
+
+func TestCutWithHole(t *testing.T) {
+	outer := makeTestCube(Vec3{0, 0, 0}, 3)
+	inner := makeTestCube(Vec3{1, 1, 0}, 1)
+	inner.Stretch(Vec3{1, 1, 5})
+	inner.Translate(Vec3{0, 0, -1})
+	hollow, err := outer.Difference(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	above, below := hollow.Cut(Vec3{0, 0, 1}, Vec3{1, 1, 1})
+	for name, part := range map[string]*Solid{"above": above, "below": below} {
+		if errors := part.Validate(); len(errors) != 0 {
+			t.Errorf("%s: not valid: %v", name, errors)
+		}
+	}
+	if v := above.Volume() + below.Volume(); !almostEqual64(v, 24, 1e-4) {
+		t.Errorf("expected total volume 24, got %g", v)
+	}
+}
+
+func TestCutWithPins(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 4)
+	above, below, err := s.CutWithOptions(Vec3{0, 0, 2}, Vec3{0, 0, 1}, CutOptions{
+		PinPositions: []Vec3{{1, 1, 0}, {3, 3, 7}},
+		PinDiameter:  1,
+		PinDepth:     1,
+		PinSides:     8,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two pins, with an octagon of circumradius 0.5 as base
+	pinVolume := 2 * 2 * 0.5 * 0.5 * 1.4142135623730951
+	for name, part := range map[string]*Solid{"above": above, "below": below} {
+		if errors := part.Validate(); len(errors) != 0 {
+			t.Errorf("%s: not valid: %v", name, errors)
+		}
+		if v := part.Volume(); !almostEqual64(v, 32-pinVolume, 1e-4) {
+			t.Errorf("%s: expected volume %g, got %g", name, 32-pinVolume, v)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package stl
+
+// This file contains closest point and signed distance queries
+
+import (
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// SignMode selects how signed distances decide whether a point is inside.
+type SignMode int
+
+const (
+	// SignPseudoNormal uses the angle weighted pseudo normal of the face,
+	// edge, or vertex the closest point lies on, calculated from the
+	// vertices, so the stored normals do not matter. It is fast, and exact
+	// for closed solids with consistently outward facing triangles.
+	SignPseudoNormal SignMode = iota
+
+	// SignWindingNumber uses the generalized winding number, like
+	// Solid.Contains. It also works for solids with holes, inconsistent
+	// orientation, or overlapping shells, but takes time proportional to
+	// the number of triangles for every point.
+	SignWindingNumber
+)
+
+// DistanceOptions control NewDistanceQueryWithOptions.
+type DistanceOptions struct {
+	// Sign selects how the sign of signed distances is determined.
+	Sign SignMode
+
+	// BVH controls building the BVH used for finding the closest triangle.
+	BVH BVHOptions
+}
+
+// DistanceQuery answers closest point and signed distance queries for the
+// triangles of a solid, using a BVH. Like a BVH it keeps a copy of the
+// vertices, and can be queried from many goroutines at once.
+type DistanceQuery struct {
+	bvh  *BVH
+	sign SignMode
+
+	// pseudo normals for SignPseudoNormal, indexed like in an IndexedMesh
+	faces         [][3]uint32
+	faceNormals   []vec64
+	vertexNormals []vec64
+	edgeNormals   map[[2]uint32]vec64
+}
+
+// NewDistanceQuery prepares distance queries for s, using the default
+// options.
+func NewDistanceQuery(s *Solid) *DistanceQuery {
+	return NewDistanceQueryWithOptions(s, DistanceOptions{})
+}
+
+// NewDistanceQueryWithOptions prepares distance queries for s.
+func NewDistanceQueryWithOptions(s *Solid, options DistanceOptions) *DistanceQuery {
+	q := &DistanceQuery{bvh: NewBVHWithOptions(s, options.BVH), sign: options.Sign}
+	if q.sign == SignPseudoNormal {
+		q.calculatePseudoNormals(s)
+	}
+	return q
+}
+
+// calculatePseudoNormals calculates the face normals, the vertex normals as
+// the sum of the normals of the adjacent faces weighted by their angle at
+// the vertex, and the edge normals as the sum of the normals of the adjacent
+// faces. Vertices are matched exactly, like in Validate.
+func (q *DistanceQuery) calculatePseudoNormals(s *Solid) {
+	m := NewIndexedMesh(s, 0)
+	q.faces = m.Faces
+	q.faceNormals = make([]vec64, len(m.Faces))
+	q.vertexNormals = make([]vec64, len(m.Vertices))
+	q.edgeNormals = make(map[[2]uint32]vec64, 3*len(m.Faces)/2)
+	for f, face := range m.Faces {
+		t := &q.bvh.triangles[f]
+		n := t.normal().unit()
+		q.faceNormals[f] = n
+		for i := 0; i < 3; i++ {
+			e1, e2 := t[(i+1)%3].sub(t[i]).unit(), t[(i+2)%3].sub(t[i]).unit()
+			angle := math.Acos(math.Max(-1, math.Min(1, e1.dot(e2))))
+			q.vertexNormals[face[i]] = q.vertexNormals[face[i]].add(n.scale(angle))
+			if key := undirectedEdge(face[i], face[(i+1)%3]); key[0] != key[1] {
+				q.edgeNormals[key] = q.edgeNormals[key].add(n)
+			}
+		}
+	}
+}
+
+// ClosestPoint returns the point on the triangles closest to p, the index of
+// the triangle it lies on, and its distance to p. If there are no triangles,
+// the index is -1 and the distance is infinite.
+func (q *DistanceQuery) ClosestPoint(p Vec3) (closest Vec3, triangle int, dist float64) {
+	triangle, closest, dist = q.bvh.Nearest(p)
+	return
+}
+
+// SignedDistance returns the distance of p to the closest point on the
+// triangles, negative if p is inside, and positive if it is outside. If there
+// are no triangles, it is positive infinity.
+func (q *DistanceQuery) SignedDistance(p Vec3) float64 {
+	return q.signedDistance(toVec64(p))
+}
+
+// SignedDistances returns the SignedDistance of all points, spreading the
+// work over as many goroutines as there are CPUs available.
+func (q *DistanceQuery) SignedDistances(points []Vec3) []float64 {
+	distances := make([]float64, len(points))
+	parallelChunks(len(points), 256, func(first, end int) {
+		for i := first; i < end; i++ {
+			distances[i] = q.signedDistance(toVec64(points[i]))
+		}
+	})
+	return distances
+}
+
+func (q *DistanceQuery) signedDistance(p vec64) float64 {
+	triangle, closest, distSquared := q.bvh.nearest(p)
+	if triangle < 0 {
+		return math.Inf(1)
+	}
+	dist := math.Sqrt(distSquared)
+	if dist == 0 {
+		return 0
+	}
+	if q.sign == SignWindingNumber {
+		if math.Abs(q.windingNumber(p)) >= 0.5 {
+			return -dist
+		}
+		return dist
+	}
+	if p.sub(closest).dot(q.pseudoNormal(p, triangle)) < 0 {
+		return -dist
+	}
+	return dist
+}
+
+// parallelChunks calls fn for consecutive ranges [first, end) of at most
+// chunkSize of the indices 0 to n-1, from as many goroutines as there are
+// CPUs available, and returns when all calls are done.
+func parallelChunks(n, chunkSize int, fn func(first, end int)) {
+	var next int64
+	var wg sync.WaitGroup
+	for w := 0; w < runtime.GOMAXPROCS(0); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				first := int(atomic.AddInt64(&next, int64(chunkSize))) - chunkSize
+				if first >= n {
+					return
+				}
+				end := first + chunkSize
+				if end > n {
+					end = n
+				}
+				fn(first, end)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// pseudoNormal returns the pseudo normal of the feature of the triangle that
+// is closest to p.
+func (q *DistanceQuery) pseudoNormal(p vec64, triangle int) vec64 {
+	_, feature := closestTriangleFeature(p, &q.bvh.triangles[triangle])
+	face := q.faces[triangle]
+	switch feature {
+	case 0, 1, 2:
+		return q.vertexNormals[face[feature]]
+	case featureEdge01, featureEdge12, featureEdge20:
+		i := feature - featureEdge01
+		return q.edgeNormals[undirectedEdge(face[i], face[(i+1)%3])]
+	}
+	return q.faceNormals[triangle]
+}
+
+// windingNumber returns the generalized winding number of the triangles at p,
+// see Solid.WindingNumber.
+func (q *DistanceQuery) windingNumber(p vec64) float64 {
+	var solidAngle float64
+	for i := range q.bvh.triangles {
+		t := &q.bvh.triangles[i]
+		solidAngle += triangleSolidAngle(t[0].sub(p), t[1].sub(p), t[2].sub(p))
+	}
+	return solidAngle / (2 * TwoPi)
+}
+
+// ClosestPoint returns the point on the triangles of s closest to p, the
+// index of the triangle it lies on, and its distance to p. If s has no
+// triangles, the index is -1 and the distance is infinite. It tests every
+// triangle, so for many points it is faster to use a DistanceQuery.
+func (s *Solid) ClosestPoint(p Vec3) (closest Vec3, triangle int, dist float64) {
+	o := toVec64(p)
+	c, triangle, distSquared := s.closestPoint(o)
+	if triangle < 0 {
+		return Vec3{}, -1, math.Inf(1)
+	}
+	return c.vec3(), triangle, math.Sqrt(distSquared)
+}
+
+// SignedDistance returns the distance of p to the closest point on the
+// triangles of s, negative if p is inside according to Contains, positive
+// otherwise. If s has no triangles, it is positive infinity. It tests every
+// triangle, so for many points it is faster to use a DistanceQuery.
+func (s *Solid) SignedDistance(p Vec3) float64 {
+	_, triangle, distSquared := s.closestPoint(toVec64(p))
+	if triangle < 0 {
+		return math.Inf(1)
+	}
+	dist := math.Sqrt(distSquared)
+	if dist > 0 && s.Contains(p) {
+		return -dist
+	}
+	return dist
+}
+
+// closestPoint returns the point on the triangles of s closest to p, the
+// index of its triangle, and the square of its distance to p.
+func (s *Solid) closestPoint(p vec64) (closest vec64, triangle int, distSquared float64) {
+	triangle, distSquared = -1, math.Inf(1)
+	for i := range s.Triangles {
+		t := s.Triangles[i].triangle64()
+		c := closestPointOnTriangle(p, &t)
+		if d := c.sub(p); d.dot(d) < distSquared {
+			closest, triangle, distSquared = c, i, d.dot(d)
+		}
+	}
+	return
+}
@@ -0,0 +1,73 @@
+package stl
+
+// Tests for closest point and signed distance queries
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestClosestPointCube(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	q := NewDistanceQuery(s)
+	for _, tc := range []struct {
+		p, closest Vec3
+		signed     float64
+	}{
+		{Vec3{0.5, 0.5, 3}, Vec3{0.5, 0.5, 1}, 2},                // face
+		{Vec3{2, 0.5, 2}, Vec3{1, 0.5, 1}, math.Sqrt(2)},         // edge
+		{Vec3{-1, -1, -1}, Vec3{0, 0, 0}, math.Sqrt(3)},          // vertex
+		{Vec3{1, 1, 1.5}, Vec3{1, 1, 1}, 0.5},                    // above vertex
+		{Vec3{0.5, 0.5, 0.75}, Vec3{0.5, 0.5, 1}, -0.25},         // inside
+		{Vec3{0.125, 0.25, 0.375}, Vec3{0, 0.25, 0.375}, -0.125}, // inside
+		{Vec3{0.5, 0.5, 1}, Vec3{0.5, 0.5, 1}, 0},                // on the surface
+	} {
+		for name, f := range map[string]func(Vec3) (Vec3, int, float64){"query": q.ClosestPoint, "solid": s.ClosestPoint} {
+			c, i, d := f(tc.p)
+			if i < 0 || !c.AlmostEqual(tc.closest, 1e-6) || !almostEqual64(d, math.Abs(tc.signed), 1e-6) {
+				t.Errorf("%s %v: expected %v at %g, got %v at %g on %d", name, tc.p, tc.closest, math.Abs(tc.signed), c, d, i)
+			}
+		}
+		if d := q.SignedDistance(tc.p); !almostEqual64(d, tc.signed, 1e-6) {
+			t.Errorf("%v: expected signed distance %g, got %g", tc.p, tc.signed, d)
+		}
+		if d := s.SignedDistance(tc.p); !almostEqual64(d, tc.signed, 1e-6) {
+			t.Errorf("%v: expected solid signed distance %g, got %g", tc.p, tc.signed, d)
+		}
+	}
+}
+
+func TestSignedDistanceTorus(t *testing.T) {
+	s := makeTestTorus(3, 1, 48, 24)
+	pseudo := NewDistanceQuery(s)
+	winding := NewDistanceQueryWithOptions(s, DistanceOptions{Sign: SignWindingNumber})
+	rnd := rand.New(rand.NewSource(1))
+	points := make([]Vec3, 2000)
+	for i := range points {
+		points[i] = Vec3{float32(rnd.Float64()*10 - 5), float32(rnd.Float64()*10 - 5), float32(rnd.Float64()*3 - 1.5)}
+	}
+	bulk := pseudo.SignedDistances(points)
+	for i, p := range points {
+		_, _, want := s.ClosestPoint(p)
+		if s.Contains(p) {
+			want = -want
+		}
+		if !almostEqual64(bulk[i], want, 1e-9) {
+			t.Errorf("%v: expected %g, got %g", p, want, bulk[i])
+		}
+		if d := winding.SignedDistance(p); !almostEqual64(d, want, 1e-9) {
+			t.Errorf("%v: expected %g by winding number, got %g", p, want, d)
+		}
+	}
+}
+
+func TestSignedDistanceEmpty(t *testing.T) {
+	q := NewDistanceQuery(&Solid{})
+	if _, i, d := q.ClosestPoint(Vec3{}); i != -1 || !math.IsInf(d, 1) {
+		t.Errorf("expected no closest point, got %d %g", i, d)
+	}
+	if d := q.SignedDistance(Vec3{}); !math.IsInf(d, 1) {
+		t.Errorf("expected infinite distance, got %g", d)
+	}
+}
@@ -58,6 +58,15 @@ rotated for 360° not to end up at exactly the original coordinates, but instead
 just very close to them. As the error is usually far smaller than the available
 precision of 3D printing applications, this is not an issue in most cases.
 
+Non-Finite Values
+
+Neither STL variant prevents NaN or ±Inf values from being stored. By default
+they are read without complaint, but ReadFileWithOptions and friends can
+reject triangles containing them, drop them, or replace the values by 0,
+see ReadOptions. Solid.CheckFinite lists the affected triangles of a Solid
+already in memory. Writing such triangles in the binary format fails with
+a *NonFiniteError, unless WriteOptions.AllowNonFinite is set.
+
 Stream Processing
 
 You can implement the Writer interface to directly write into your own data structures.
@@ -0,0 +1,249 @@
+package stl
+
+// This file defines the Drawing data type for 2D output of slices and
+// projections
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"os"
+)
+
+// Drawing is a 2D drawing made of polylines, e.g. the contours of a Layer,
+// or a projection of a solid. It can be written as SVG or DXF.
+type Drawing struct {
+	Polylines []Polyline
+}
+
+// Polyline is a sequence of connected points in a Drawing.
+type Polyline struct {
+	Points [][2]float64
+
+	// Closed is true if the last point is connected to the first one.
+	Closed bool
+}
+
+// DrawingOptions control writing a Drawing.
+type DrawingOptions struct {
+	// Scale is multiplied with all coordinates, e.g. to convert model units
+	// into the drawing unit. The default 0 means 1.
+	Scale float64
+
+	// Unit is the unit of the scaled coordinates in SVG files, one of "mm",
+	// "cm", "in", "pt", "pc", and "px". The default "" means "mm". DXF R12
+	// has no units, the scaled coordinates are written as they are.
+	Unit string
+
+	// Margin is added around the drawing in SVG files, in Unit.
+	Margin float64
+
+	// StrokeWidth is the width of the lines in SVG files, in Unit. The
+	// default 0 means 0.1.
+	StrokeWidth float64
+}
+
+// Drawing returns the contours of the layer as a drawing, closed contours
+// as closed polylines, open contours as open ones. The coordinates are taken
+// along two orthogonal axes in the plane, so that the drawing shows the
+// layer as seen from the side the normal points to. For a layer orthogonal to
+// the z axis created by SliceZ, these are the x and y coordinates of the solid.
+func (layer *Layer) Drawing() *Drawing {
+	u, v := planeBasis(toVec64(layer.Normal).unit())
+	project := func(points []Vec3) [][2]float64 {
+		projected := make([][2]float64, len(points))
+		for i, p := range points {
+			q := toVec64(p)
+			projected[i] = [2]float64{q.dot(u), q.dot(v)}
+		}
+		return projected
+	}
+	d := &Drawing{}
+	for _, c := range layer.Contours {
+		d.Polylines = append(d.Polylines, Polyline{Points: project(c.Points), Closed: true})
+	}
+	for _, points := range layer.OpenContours {
+		d.Polylines = append(d.Polylines, Polyline{Points: project(points)})
+	}
+	return d
+}
+
+// Silhouette returns the orthographic projection of the outline of s, when
+// looking into viewDirection. It consists of the edges between triangles
+// facing the viewer and triangles facing away, and of all edges not shared
+// by exactly two triangles. See Projection for the coordinates used.
+func (s *Solid) Silhouette(viewDirection Vec3) *Drawing {
+	return s.Projection(viewDirection, math.Inf(1))
+}
+
+// Projection returns the orthographic projection of s, when looking into
+// viewDirection, showing the silhouette like Silhouette, and the edges
+// between triangles whose normals differ by more than featureAngle (in
+// radians), if at least one of them faces the viewer. Hidden edges are not
+// removed. Vertices are matched exactly, like in Validate.
+//
+// The coordinates are taken along two orthogonal axes orthogonal to
+// viewDirection, so that the drawing shows s as seen by the viewer. When
+// looking down the z axis, i.e. into direction {0, 0, -1}, these are the x
+// and y coordinates of the solid.
+func (s *Solid) Projection(viewDirection Vec3, featureAngle float64) *Drawing {
+	towardsViewer := toVec64(viewDirection).unit().scale(-1)
+	u, v := planeBasis(towardsViewer)
+	m := NewIndexedMesh(s, 0)
+
+	normals := make([]vec64, len(m.Faces))
+	facing := make([]bool, len(m.Faces))
+	edgeFaces := make(map[[2]uint32][]int, 3*len(m.Faces)/2)
+	var edges [][2]uint32
+	for f, face := range m.Faces {
+		a, b, c := toVec64(m.Vertices[face[0]]), toVec64(m.Vertices[face[1]]), toVec64(m.Vertices[face[2]])
+		normals[f] = b.sub(a).cross(c.sub(a)).unit()
+		facing[f] = normals[f].dot(towardsViewer) > 0
+		for i := 0; i < 3; i++ {
+			key := undirectedEdge(face[i], face[(i+1)%3])
+			if key[0] == key[1] {
+				continue
+			}
+			if _, found := edgeFaces[key]; !found {
+				edges = append(edges, key)
+			}
+			edgeFaces[key] = append(edgeFaces[key], f)
+		}
+	}
+	cosFeature := math.Cos(featureAngle)
+
+	var visible [][2]uint32
+	for _, e := range edges {
+		faces := edgeFaces[e]
+		if len(faces) == 2 {
+			f, g := faces[0], faces[1]
+			if facing[f] == facing[g] &&
+				(!facing[f] || featureAngle > Pi || normals[f].dot(normals[g]) >= cosFeature) {
+				continue
+			}
+		}
+		visible = append(visible, e)
+	}
+
+	d := &Drawing{}
+	for _, path := range chainEdges(visible) {
+		points := make([][2]float64, len(path.vertices))
+		for i, idx := range path.vertices {
+			p := toVec64(m.Vertices[idx])
+			points[i] = [2]float64{p.dot(u), p.dot(v)}
+		}
+		d.Polylines = append(d.Polylines, Polyline{Points: points, Closed: path.closed})
+	}
+	return d
+}
+
+// edgePath is a sequence of vertex indices connected by edges.
+type edgePath struct {
+	vertices []uint32
+	closed   bool
+}
+
+// chainEdges connects edges into paths. Paths end at vertices not used by
+// exactly two edges, the remaining edges form closed paths.
+func chainEdges(edges [][2]uint32) []edgePath {
+	vertexEdges := make(map[uint32][]int)
+	for i, e := range edges {
+		vertexEdges[e[0]] = append(vertexEdges[e[0]], i)
+		vertexEdges[e[1]] = append(vertexEdges[e[1]], i)
+	}
+	used := make([]bool, len(edges))
+	follow := func(start uint32, first int) []uint32 {
+		path := []uint32{start}
+		v, i := start, first
+		for i >= 0 {
+			used[i] = true
+			if edges[i][0] == v {
+				v = edges[i][1]
+			} else {
+				v = edges[i][0]
+			}
+			path = append(path, v)
+			i = -1
+			if len(vertexEdges[v]) == 2 {
+				for _, j := range vertexEdges[v] {
+					if !used[j] {
+						i = j
+					}
+				}
+			}
+		}
+		return path
+	}
+
+	var paths []edgePath
+	for _, e := range edges {
+		for _, v := range e {
+			if len(vertexEdges[v]) == 2 {
+				continue
+			}
+			for _, i := range vertexEdges[v] {
+				if !used[i] {
+					paths = append(paths, edgePath{vertices: follow(v, i)})
+				}
+			}
+		}
+	}
+	for i, e := range edges {
+		if !used[i] {
+			path := follow(e[0], i)
+			paths = append(paths, edgePath{vertices: path[:len(path)-1], closed: true})
+		}
+	}
+	return paths
+}
+
+// bounds returns the minimum and maximum coordinates of all points.
+func (d *Drawing) bounds() (min, max [2]float64) {
+	min = [2]float64{math.Inf(1), math.Inf(1)}
+	max = [2]float64{math.Inf(-1), math.Inf(-1)}
+	for _, p := range d.Polylines {
+		for _, q := range p.Points {
+			for i := 0; i < 2; i++ {
+				min[i] = math.Min(min[i], q[i])
+				max[i] = math.Max(max[i], q[i])
+			}
+		}
+	}
+	if min[0] > max[0] {
+		return [2]float64{}, [2]float64{}
+	}
+	return min, max
+}
+
+// WriteSVGFile writes the drawing to an SVG file, see WriteSVG.
+func (d *Drawing) WriteSVGFile(filename string, options DrawingOptions) error {
+	return writeDrawingFile(filename, func(w io.Writer) error {
+		return d.WriteSVG(w, options)
+	})
+}
+
+// WriteDXFFile writes the drawing to a DXF file, see WriteDXF.
+func (d *Drawing) WriteDXFFile(filename string, options DrawingOptions) error {
+	return writeDrawingFile(filename, func(w io.Writer) error {
+		return d.WriteDXF(w, options)
+	})
+}
+
+func writeDrawingFile(filename string, write func(w io.Writer) error) (err error) {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	bufWriter := bufio.NewWriter(file)
+	err = write(bufWriter)
+	flushErr := bufWriter.Flush()
+	closeErr := file.Close()
+	if err == nil {
+		err = flushErr
+	}
+	if err == nil {
+		err = closeErr
+	}
+	return
+}
@@ -0,0 +1,102 @@
+package stl
+
+// Tests for drawings and their SVG and DXF output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLayerDrawing(t *testing.T) {
+	s := makeTestCube(Vec3{1, 2, 0}, 1)
+	d := s.SliceZ(1)[0].Drawing()
+	if len(d.Polylines) != 1 || !d.Polylines[0].Closed {
+		t.Fatalf("expected one closed polyline, got %v", d.Polylines)
+	}
+	min, max := d.bounds()
+	if min != [2]float64{1, 2} || max != [2]float64{2, 3} {
+		t.Errorf("expected drawing in x and y of solid, got bounds %v %v", min, max)
+	}
+}
+
+func TestProjection(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	d := s.Silhouette(Vec3{0, 0, -1})
+	if len(d.Polylines) != 1 || !d.Polylines[0].Closed || len(d.Polylines[0].Points) != 4 {
+		t.Fatalf("expected a closed square as silhouette, got %v", d.Polylines)
+	}
+	min, max := d.bounds()
+	if min != [2]float64{0, 0} || max != [2]float64{1, 1} {
+		t.Errorf("unexpected bounds %v %v", min, max)
+	}
+
+	// looking at a corner, the silhouette is a hexagon, and three more
+	// edges are visible
+	view := Vec3{-1, -1, -1}
+	if d := s.Silhouette(view); len(d.Polylines) != 1 || len(d.Polylines[0].Points) != 6 {
+		t.Errorf("expected hexagon as silhouette, got %v", d.Polylines)
+	}
+	edges := 0
+	for _, p := range s.Projection(view, 0.1).Polylines {
+		edges += len(p.Points) - 1
+		if p.Closed {
+			edges++
+		}
+	}
+	if edges != 9 {
+		t.Errorf("expected 9 visible edges, got %d", edges)
+	}
+}
+
+func TestDrawingWriteSVG(t *testing.T) {
+	d := &Drawing{Polylines: []Polyline{
+		{Points: [][2]float64{{0, 0}, {2, 0}, {2, 1}}, Closed: true},
+		{Points: [][2]float64{{0, 1}, {1, 1}}},
+	}}
+	var buf bytes.Buffer
+	if err := d.WriteSVG(&buf, DrawingOptions{Scale: 10, Unit: "in", Margin: 1}); err != nil {
+		t.Fatal(err)
+	}
+	svg := buf.String()
+	for _, expected := range []string{
+		`width="22in" height="12in" viewBox="0 0 22 12"`,
+		`<path d="M1 11 L21 11 L21 1 Z"/>`,
+		`<path d="M1 1 L11 1"/>`,
+	} {
+		if !strings.Contains(svg, expected) {
+			t.Errorf("expected %s in SVG:\n%s", expected, svg)
+		}
+	}
+	if err := d.WriteSVG(&buf, DrawingOptions{Unit: "furlong"}); err == nil {
+		t.Error("expected error for unknown unit")
+	}
+}
+
+func TestDrawingWriteDXF(t *testing.T) {
+	d := &Drawing{Polylines: []Polyline{
+		{Points: [][2]float64{{0, 0}, {2, 0}, {2, 1.5}}, Closed: true},
+	}}
+	var buf bytes.Buffer
+	if err := d.WriteDXF(&buf, DrawingOptions{Scale: 2}); err != nil {
+		t.Fatal(err)
+	}
+	dxf := buf.String()
+	for _, expected := range []string{
+		"9\n$ACADVER\n1\nAC1009\n",
+		"9\n$EXTMAX\n10\n4\n20\n3\n",
+		"0\nPOLYLINE\n8\n0\n66\n1\n70\n1\n",
+		"0\nVERTEX\n8\n0\n10\n4\n20\n3\n",
+		"0\nSEQEND\n",
+	} {
+		if !strings.Contains(dxf, expected) {
+			t.Errorf("expected %q in DXF:\n%s", expected, dxf)
+		}
+	}
+	if !strings.HasSuffix(dxf, "0\nEOF\n") {
+		t.Error("DXF does not end with EOF")
+	}
+	if n := strings.Count(dxf, "VERTEX"); n != 3 {
+		t.Errorf("expected 3 vertices, got %d", n)
+	}
+}
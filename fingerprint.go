@@ -0,0 +1,179 @@
+package stl
+
+// This file contains a content hash of the geometry of a solid, and sorting
+// triangles into a canonical order
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"sort"
+)
+
+// FingerprintOptions control Solid.FingerprintWithOptions.
+type FingerprintOptions struct {
+	// Tolerance is the size of the grid vertex coordinates are rounded to
+	// before hashing, so that solids whose vertices differ by much less than
+	// Tolerance get the same fingerprint. Coordinates close to the middle
+	// between two grid points may still be rounded differently. The default
+	// 0 means vertices have to be exactly equal.
+	Tolerance float64
+}
+
+// Fingerprint returns a SHA-256 hash of the geometry of s, which does not
+// depend on the order of the triangles, the cyclic order of the vertices of
+// every triangle, the name, the binary header, and whether s was read from
+// an ASCII or binary file. Normals and attributes are ignored, as they are
+// often not exported consistently. Reversing the order of the vertices of a
+// triangle flips it, and changes the fingerprint. See FingerprintWithOptions
+// for solids with slightly different vertices.
+func (s *Solid) Fingerprint() [sha256.Size]byte {
+	return s.FingerprintWithOptions(FingerprintOptions{})
+}
+
+// FingerprintWithOptions returns the fingerprint of s like Fingerprint,
+// applying options.
+func (s *Solid) FingerprintWithOptions(options FingerprintOptions) [sha256.Size]byte {
+	keys := make([]triangleKey, len(s.Triangles))
+	for i := range s.Triangles {
+		keys[i] = s.Triangles[i].key(options.Tolerance)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].less(&keys[j])
+	})
+
+	h := sha256.New()
+	var buf [8 * 9]byte
+	for i := range keys {
+		for c, v := range keys[i] {
+			binary.BigEndian.PutUint64(buf[8*c:], uint64(v))
+		}
+		h.Write(buf[:])
+	}
+	var fingerprint [sha256.Size]byte
+	copy(fingerprint[:], h.Sum(nil))
+	return fingerprint
+}
+
+// Canonicalize sorts the triangles of s into a canonical order, so that
+// solids with the same Fingerprint are written the same way, if they have
+// the same name, binary header, normals and attributes. The vertices of every
+// triangle are rotated to their lexicographically smallest order, without
+// changing the orientation. Triangles are ordered by their vertices, then by
+// normal and attributes. Coordinates of negative zero are replaced by zero.
+func (s *Solid) Canonicalize() {
+	for i := range s.Triangles {
+		t := &s.Triangles[i]
+		for v := 0; v < 3; v++ {
+			t.Vertices[v] = withoutNegativeZero(t.Vertices[v])
+		}
+		t.Normal = withoutNegativeZero(t.Normal)
+		vs := t.Vertices
+		for r := 1; r < 3; r++ {
+			rotated := [3]Vec3{vs[r], vs[(r+1)%3], vs[(r+2)%3]}
+			if verticesLess(&rotated, &t.Vertices) {
+				t.Vertices = rotated
+			}
+		}
+	}
+	sort.SliceStable(s.Triangles, func(i, j int) bool {
+		a, b := &s.Triangles[i], &s.Triangles[j]
+		if a.Vertices != b.Vertices {
+			return verticesLess(&a.Vertices, &b.Vertices)
+		}
+		if a.Normal != b.Normal {
+			return vec3Less(a.Normal, b.Normal)
+		}
+		return a.Attributes < b.Attributes
+	})
+}
+
+// triangleKey contains the coordinates of the vertices of a triangle mapped
+// to integers with the same order, in the cyclic order of the vertices that
+// is lexicographically smallest.
+type triangleKey [9]int64
+
+// key returns the triangleKey of t. For tolerance > 0, the coordinates are
+// rounded to multiples of tolerance, otherwise their bits are used, with
+// negative zero replaced by zero.
+func (t *Triangle) key(tolerance float64) triangleKey {
+	var vertices [3][3]int64
+	for v := 0; v < 3; v++ {
+		for c := 0; c < 3; c++ {
+			x := t.Vertices[v][c]
+			if tolerance > 0 {
+				vertices[v][c] = int64(math.Round(float64(x) / tolerance))
+			} else {
+				vertices[v][c] = orderedFloat32Bits(x)
+			}
+		}
+	}
+	var k triangleKey
+	for r := 0; r < 3; r++ {
+		var rotated triangleKey
+		for v := 0; v < 3; v++ {
+			copy(rotated[3*v:], vertices[(r+v)%3][:])
+		}
+		if r == 0 || rotated.less(&k) {
+			k = rotated
+		}
+	}
+	return k
+}
+
+func (k *triangleKey) less(o *triangleKey) bool {
+	return lessInt64s(k[:], o[:])
+}
+
+// lessInt64s compares a and b lexicographically.
+func lessInt64s(a, b []int64) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
+
+// orderedFloat32Bits maps x to an integer, so that the order of the integers
+// is the order of the floats, and 0 and -0 map to the same value.
+func orderedFloat32Bits(x float32) int64 {
+	if x == 0 {
+		return 0
+	}
+	bits := math.Float32bits(x)
+	if bits&(1<<31) != 0 {
+		return -int64(bits &^ (1 << 31))
+	}
+	return int64(bits)
+}
+
+// vec3Less compares the coordinates of a and b lexicographically.
+func vec3Less(a, b Vec3) bool {
+	for c := 0; c < 3; c++ {
+		if a[c] != b[c] {
+			return a[c] < b[c]
+		}
+	}
+	return false
+}
+
+// verticesLess compares the vertices of a and b lexicographically.
+func verticesLess(a, b *[3]Vec3) bool {
+	for v := 0; v < 3; v++ {
+		if a[v] != b[v] {
+			return vec3Less(a[v], b[v])
+		}
+	}
+	return false
+}
+
+// withoutNegativeZero returns v with coordinates of -0 replaced by 0.
+func withoutNegativeZero(v Vec3) Vec3 {
+	for c := 0; c < 3; c++ {
+		if v[c] == 0 {
+			v[c] = 0
+		}
+	}
+	return v
+}
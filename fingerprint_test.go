@@ -0,0 +1,125 @@
+package stl
+
+// Tests for fingerprints and canonical order
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+)
+
+// shuffledTestTorus returns a test torus with triangles in random order and
+// randomly rotated vertices.
+func shuffledTestTorus(seed int64) *Solid {
+	s := makeTestTorus(3, 1, 16, 8)
+	rnd := rand.New(rand.NewSource(seed))
+	rnd.Shuffle(len(s.Triangles), func(i, j int) {
+		s.Triangles[i], s.Triangles[j] = s.Triangles[j], s.Triangles[i]
+	})
+	for i := range s.Triangles {
+		vs := s.Triangles[i].Vertices
+		r := rnd.Intn(3)
+		s.Triangles[i].Vertices = [3]Vec3{vs[r], vs[(r+1)%3], vs[(r+2)%3]}
+	}
+	return s
+}
+
+func TestFingerprintInvariance(t *testing.T) {
+	s := makeTestTorus(3, 1, 16, 8)
+	want := s.Fingerprint()
+
+	shuffled := shuffledTestTorus(1)
+	shuffled.Name = "other"
+	shuffled.BinaryHeader = []byte("some header")
+	shuffled.Triangles[0].Attributes = 7
+	shuffled.Triangles[1].Normal = Vec3{}
+	if got := shuffled.Fingerprint(); got != want {
+		t.Errorf("shuffled solid: expected fingerprint %x, got %x", want, got)
+	}
+
+	for _, isASCII := range []bool{false, true} {
+		s.IsAscii = isASCII
+		var buf bytes.Buffer
+		if err := s.WriteAll(&buf); err != nil {
+			t.Fatal(err)
+		}
+		read, err := ReadAll(bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := read.Fingerprint(); got != want {
+			t.Errorf("ascii=%v: expected fingerprint %x after reading, got %x", isASCII, want, got)
+		}
+	}
+}
+
+func TestFingerprintChanges(t *testing.T) {
+	want := makeTestTorus(3, 1, 16, 8).Fingerprint()
+
+	moved := makeTestTorus(3, 1, 16, 8)
+	moved.Triangles[3].Vertices[1][2] += 0.001
+	if moved.Fingerprint() == want {
+		t.Error("expected different fingerprint after moving a vertex")
+	}
+
+	flipped := makeTestTorus(3, 1, 16, 8)
+	vs := &flipped.Triangles[5].Vertices
+	vs[1], vs[2] = vs[2], vs[1]
+	if flipped.Fingerprint() == want {
+		t.Error("expected different fingerprint after flipping a triangle")
+	}
+
+	removed := makeTestTorus(3, 1, 16, 8)
+	removed.Triangles = removed.Triangles[1:]
+	if removed.Fingerprint() == want {
+		t.Error("expected different fingerprint after removing a triangle")
+	}
+}
+
+func TestFingerprintTolerance(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	b := makeTestCube(Vec3{0, 0, 0}, 1)
+	b.Triangles[0].Vertices[0][0] += 1e-5
+	b.Triangles[2].Vertices[1][1] -= 1e-5
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("expected different exact fingerprints")
+	}
+	options := FingerprintOptions{Tolerance: 0.001}
+	if a.FingerprintWithOptions(options) != b.FingerprintWithOptions(options) {
+		t.Error("expected equal fingerprints with tolerance")
+	}
+}
+
+func TestCanonicalize(t *testing.T) {
+	a, b := shuffledTestTorus(1), shuffledTestTorus(2)
+	a.Canonicalize()
+	b.Canonicalize()
+	var bufA, bufB bytes.Buffer
+	if err := a.WriteAll(&bufA); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.WriteAll(&bufB); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bufA.Bytes(), bufB.Bytes()) {
+		t.Error("expected equal output after Canonicalize")
+	}
+	if a.Fingerprint() != makeTestTorus(3, 1, 16, 8).Fingerprint() {
+		t.Error("Canonicalize changed the fingerprint")
+	}
+	if errors := a.Validate(); len(errors) != 0 {
+		t.Errorf("Canonicalize broke the solid: %v", errors)
+	}
+}
+
+func TestCanonicalizeNegativeZero(t *testing.T) {
+	negZero := float32(0)
+	negZero = -negZero
+	s := &Solid{Triangles: []Triangle{{Vertices: [3]Vec3{{1, 0, 0}, {negZero, 1, 0}, {0, negZero, 0}}}}}
+	fp := s.Fingerprint()
+	s.Canonicalize()
+	want := [3]Vec3{{0, 0, 0}, {1, 0, 0}, {0, 1, 0}}
+	if s.Triangles[0].Vertices != want || s.Fingerprint() != fp {
+		t.Errorf("expected vertices %v, got %v", want, s.Triangles[0].Vertices)
+	}
+}
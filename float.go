@@ -49,3 +49,8 @@ func max(a, b float32) float32 {
 func max4(a, b, c, d float32) float32 {
 	return max(max(a, b), max(c, d))
 }
+
+// isFinite32 returns true, if f is neither NaN nor ±Inf.
+func isFinite32(f float32) bool {
+	return !math.IsNaN(float64(f)) && !math.IsInf(float64(f), 0)
+}
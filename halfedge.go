@@ -0,0 +1,260 @@
+package stl
+
+// This file defines the HalfEdgeMesh data type used to navigate the
+// topology of a solid
+
+// HalfEdge is a directed edge of a face in a HalfEdgeMesh. Every face has
+// three half-edges, running along its vertices in their right hand order.
+type HalfEdge struct {
+	// Vertex is the index in HalfEdgeMesh.Vertices of the vertex the half-edge starts at.
+	Vertex int
+
+	// Face is the index of the face the half-edge belongs to.
+	Face int
+
+	// Next is the index of the following half-edge of the same face.
+	Next int
+
+	// Twin is the index of the half-edge of the neighboring face running in
+	// the opposite direction. It is -1 if there is no neighboring face, or
+	// if the edge is non-manifold.
+	Twin int
+}
+
+// HalfEdgeMesh extends an IndexedMesh by half-edges that allow navigating
+// from a face to its neighbors, and around a vertex. The half-edges of face f
+// have the indices 3*f, 3*f+1, and 3*f+2, starting at the vertices
+// Faces[f][0], Faces[f][1], and Faces[f][2].
+//
+// An edge is manifold if it is used by exactly two faces in opposite
+// directions, and then its two half-edges are twins. An edge used by only one
+// face is a boundary edge. All other edges are non-manifold, they are used by
+// more than two faces, or by two faces with inconsistent orientation. Faces
+// referring to the same vertex more than once are degenerate, their half-edges
+// never have twins, and are neither boundary nor non-manifold edges.
+type HalfEdgeMesh struct {
+	IndexedMesh
+
+	// HalfEdges contains three half-edges per face.
+	HalfEdges []HalfEdge
+
+	// vertexHalfEdge contains an outgoing half-edge for every vertex, or -1.
+	// Boundary and non-manifold half-edges are preferred, so rotating around
+	// the vertex starting with it covers a whole fan of faces.
+	vertexHalfEdge []int
+
+	// nonManifold is true for every half-edge of a non-manifold edge.
+	nonManifold []bool
+}
+
+// NewHalfEdgeMesh creates a HalfEdgeMesh from the triangles in s. Vertices
+// are welded like in NewIndexedMesh.
+func NewHalfEdgeMesh(s *Solid, weldTolerance float32) *HalfEdgeMesh {
+	return newHalfEdgeMesh(NewIndexedMesh(s, weldTolerance))
+}
+
+// newHalfEdgeMesh builds the half-edges for im, which becomes part of the
+// result and must not be changed afterwards.
+func newHalfEdgeMesh(im *IndexedMesh) *HalfEdgeMesh {
+	m := HalfEdgeMesh{
+		IndexedMesh:    *im,
+		HalfEdges:      make([]HalfEdge, 3*len(im.Faces)),
+		vertexHalfEdge: make([]int, len(im.Vertices)),
+		nonManifold:    make([]bool, 3*len(im.Faces)),
+	}
+
+	directed := make(map[[2]int][]int, 3*len(im.Faces))
+	for f, face := range m.Faces {
+		degenerate := m.isDegenerateFace(f)
+		for v := 0; v < 3; v++ {
+			h := 3*f + v
+			m.HalfEdges[h] = HalfEdge{
+				Vertex: int(face[v]),
+				Face:   f,
+				Next:   3*f + (v+1)%3,
+				Twin:   -1,
+			}
+			if !degenerate {
+				key := [2]int{int(face[v]), int(face[(v+1)%3])}
+				directed[key] = append(directed[key], h)
+			}
+		}
+	}
+
+	for key, hs := range directed {
+		counter := directed[[2]int{key[1], key[0]}]
+		switch {
+		case len(hs) == 1 && len(counter) == 1:
+			m.HalfEdges[hs[0]].Twin = counter[0]
+		case len(hs) == 1 && len(counter) == 0:
+			// boundary edge
+		default:
+			for _, h := range hs {
+				m.nonManifold[h] = true
+			}
+		}
+	}
+
+	for v := range m.vertexHalfEdge {
+		m.vertexHalfEdge[v] = -1
+	}
+	for h, he := range m.HalfEdges {
+		if m.isDegenerateFace(he.Face) {
+			continue
+		}
+		current := m.vertexHalfEdge[he.Vertex]
+		if current < 0 || (m.HalfEdges[current].Twin >= 0 && he.Twin < 0) {
+			m.vertexHalfEdge[he.Vertex] = h
+		}
+	}
+	return &m
+}
+
+// isDegenerateFace returns true if face f refers to the same vertex more than once.
+func (m *HalfEdgeMesh) isDegenerateFace(f int) bool {
+	face := m.Faces[f]
+	return face[0] == face[1] || face[0] == face[2] || face[1] == face[2]
+}
+
+// Prev returns the index of the half-edge preceding h in its face.
+func (m *HalfEdgeMesh) Prev(h int) int {
+	return 3*(h/3) + (h+2)%3
+}
+
+// Target returns the index of the vertex half-edge h points to.
+func (m *HalfEdgeMesh) Target(h int) int {
+	return m.HalfEdges[m.HalfEdges[h].Next].Vertex
+}
+
+// VertexHalfEdge returns the index of a half-edge starting at vertex v, or -1
+// if v is not used by any non-degenerate face. For vertices on the boundary,
+// a boundary half-edge is returned.
+func (m *HalfEdgeMesh) VertexHalfEdge(v int) int {
+	return m.vertexHalfEdge[v]
+}
+
+// IsBoundary returns true if half-edge h belongs to an edge used by only one face.
+func (m *HalfEdgeMesh) IsBoundary(h int) bool {
+	return m.HalfEdges[h].Twin < 0 && !m.nonManifold[h] && !m.isDegenerateFace(m.HalfEdges[h].Face)
+}
+
+// IsNonManifold returns true if half-edge h belongs to a non-manifold edge.
+func (m *HalfEdgeMesh) IsNonManifold(h int) bool {
+	return m.nonManifold[h]
+}
+
+// fan calls fn for every half-edge starting at vertex v that can be reached
+// by rotating around v from VertexHalfEdge(v), in counter-clockwise order
+// seen from outside. Returns the number of half-edges visited.
+func (m *HalfEdgeMesh) fan(v int, fn func(h int)) int {
+	start := m.vertexHalfEdge[v]
+	if start < 0 {
+		return 0
+	}
+	n := 0
+	for h := start; n < len(m.HalfEdges); {
+		fn(h)
+		n++
+		h = m.HalfEdges[m.Prev(h)].Twin
+		if h < 0 || h == start {
+			break
+		}
+	}
+	return n
+}
+
+// OneRing returns the indices of the vertices connected to vertex v by an edge,
+// in counter-clockwise order seen from outside. For a non-manifold vertex, only
+// the vertices of one fan of faces are returned.
+func (m *HalfEdgeMesh) OneRing(v int) []int {
+	var ring []int
+	last := -1
+	m.fan(v, func(h int) {
+		ring = append(ring, m.Target(h))
+		last = h
+	})
+	if last >= 0 && m.HalfEdges[m.Prev(last)].Twin < 0 {
+		// open fan, the preceding half-edge of the last face ends at v
+		ring = append(ring, m.HalfEdges[m.Prev(last)].Vertex)
+	}
+	return ring
+}
+
+// NonManifoldEdges returns the indices of all half-edges belonging to non-manifold edges.
+func (m *HalfEdgeMesh) NonManifoldEdges() []int {
+	var hs []int
+	for h, nm := range m.nonManifold {
+		if nm {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
+
+// NonManifoldVertices returns the indices of all vertices whose faces do
+// not form a single fan, i.e. where the surface is pinched together.
+func (m *HalfEdgeMesh) NonManifoldVertices() []int {
+	outgoing := make([]int, len(m.Vertices))
+	for _, he := range m.HalfEdges {
+		if !m.isDegenerateFace(he.Face) {
+			outgoing[he.Vertex]++
+		}
+	}
+	var vs []int
+	for v, n := range outgoing {
+		if n > 0 && m.fan(v, func(int) {}) < n {
+			vs = append(vs, v)
+		}
+	}
+	return vs
+}
+
+// BoundaryLoops returns the boundary edges, assembled into loops. Every
+// loop is given by the indices of its half-edges, each one starting at the
+// vertex the previous one ends at. The faces are to the left of the loop,
+// when looking at them from outside. Loops passing through non-manifold
+// vertices or edges may not be closed.
+func (m *HalfEdgeMesh) BoundaryLoops() [][]int {
+	used := make([]bool, len(m.HalfEdges))
+	boundaryFrom := make(map[int][]int)
+	for h := range m.HalfEdges {
+		if m.IsBoundary(h) {
+			v := m.HalfEdges[h].Vertex
+			boundaryFrom[v] = append(boundaryFrom[v], h)
+		}
+	}
+
+	var loops [][]int
+	for h := range m.HalfEdges {
+		if used[h] || !m.IsBoundary(h) {
+			continue
+		}
+		var loop []int
+		for h >= 0 && !used[h] {
+			used[h] = true
+			loop = append(loop, h)
+			h = m.nextBoundary(h, used, boundaryFrom)
+		}
+		loops = append(loops, loop)
+	}
+	return loops
+}
+
+// nextBoundary returns an unused boundary half-edge starting where boundary
+// half-edge h ends, or -1. The one reached by rotating around the vertex
+// within the same fan of faces is preferred.
+func (m *HalfEdgeMesh) nextBoundary(h int, used []bool, boundaryFrom map[int][]int) int {
+	g := m.HalfEdges[h].Next
+	for i := 0; i < len(m.HalfEdges) && m.HalfEdges[g].Twin >= 0; i++ {
+		g = m.HalfEdges[m.HalfEdges[g].Twin].Next
+	}
+	if m.IsBoundary(g) && !used[g] {
+		return g
+	}
+	for _, g := range boundaryFrom[m.Target(h)] {
+		if !used[g] {
+			return g
+		}
+	}
+	return -1
+}
@@ -0,0 +1,95 @@
+package stl
+
+// Tests for the HalfEdgeMesh data type
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHalfEdgeMeshClosed(t *testing.T) {
+	m := NewHalfEdgeMesh(makeTestSolid(), 0)
+	for h, he := range m.HalfEdges {
+		if he.Twin < 0 {
+			t.Fatalf("half-edge %d has no twin", h)
+		}
+		if m.HalfEdges[he.Twin].Twin != h {
+			t.Errorf("twin of twin of half-edge %d is not %d", h, h)
+		}
+		if m.HalfEdges[he.Twin].Vertex != m.Target(h) {
+			t.Errorf("twin of half-edge %d does not start at its target", h)
+		}
+	}
+	if loops := m.BoundaryLoops(); len(loops) != 0 {
+		t.Errorf("expected no boundary loops, got %v", loops)
+	}
+	if nm := m.NonManifoldEdges(); len(nm) != 0 {
+		t.Errorf("expected no non-manifold edges, got %v", nm)
+	}
+	if nm := m.NonManifoldVertices(); len(nm) != 0 {
+		t.Errorf("expected no non-manifold vertices, got %v", nm)
+	}
+	// vertex 0 is {0, 0, 0}, its neighbors seen from outside counter-clockwise
+	if ring := m.OneRing(0); !reflect.DeepEqual(ring, []int{1, 2, 3}) &&
+		!reflect.DeepEqual(ring, []int{2, 3, 1}) && !reflect.DeepEqual(ring, []int{3, 1, 2}) {
+		t.Errorf("unexpected one-ring of vertex 0: %v", ring)
+	}
+}
+
+func TestHalfEdgeMeshBoundary(t *testing.T) {
+	s := makeTestSolid()
+	s.Triangles = s.Triangles[1:]
+	m := NewHalfEdgeMesh(s, 0)
+	loops := m.BoundaryLoops()
+	if len(loops) != 1 || len(loops[0]) != 3 {
+		t.Fatalf("expected one boundary loop of 3 edges, got %v", loops)
+	}
+	for i, h := range loops[0] {
+		next := loops[0][(i+1)%3]
+		if m.Target(h) != m.HalfEdges[next].Vertex {
+			t.Errorf("boundary loop %v is not connected", loops[0])
+		}
+	}
+	// vertex 0 is on the boundary, all its neighbors have to be in the ring
+	if ring := m.OneRing(0); len(ring) != 3 {
+		t.Errorf("expected 3 vertices in one-ring of boundary vertex 0, got %v", ring)
+	}
+}
+
+func TestHalfEdgeMeshNonManifold(t *testing.T) {
+	s := makeTestSolid()
+	s.Triangles = append(s.Triangles, s.Triangles[0])
+	m := NewHalfEdgeMesh(s, 0)
+	// the three edges of the duplicated face are used by three faces each
+	if nm := m.NonManifoldEdges(); len(nm) != 9 {
+		t.Errorf("expected 9 non-manifold half-edges, got %v", nm)
+	}
+
+	// two tetrahedra touching in the origin
+	s = makeTestSolid()
+	other := makeTestSolid()
+	other.Translate(Vec3{0, 0, -1})
+	s.Triangles = append(s.Triangles, other.Triangles...)
+	m = NewHalfEdgeMesh(s, 0)
+	if nm := m.NonManifoldEdges(); len(nm) != 0 {
+		t.Errorf("expected no non-manifold edges, got %v", nm)
+	}
+	if nm := m.NonManifoldVertices(); len(nm) != 1 || m.Vertices[nm[0]] != vec3Zero {
+		t.Errorf("expected the origin as only non-manifold vertex, got %v", nm)
+	}
+}
+
+func TestHalfEdgeMeshSolid(t *testing.T) {
+	s := makeTestSolid()
+	for i := range s.Triangles {
+		s.Triangles[i].Attributes = uint16(i + 1)
+	}
+	back := NewHalfEdgeMesh(s, 0).Solid()
+	back.Name = s.Name
+	back.IsAscii = s.IsAscii
+	if !s.sameOrderAlmostEqual(back) {
+		t.Error("Not equal after round trip")
+		t.Log("Expected:\n", s)
+		t.Log("Found:\n", back)
+	}
+}
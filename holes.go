@@ -0,0 +1,172 @@
+package stl
+
+// This file contains hole detection and filling
+
+import (
+	"math"
+)
+
+// Holes returns the holes in the solid, i.e. closed loops of edges used by
+// only one triangle, each given by the vertices along the loop. The adjacent
+// triangles are to the left of the loop, when looking at them from outside.
+// Vertices are matched exactly, like in Validate.
+func (s *Solid) Holes() [][]Vec3 {
+	m := NewHalfEdgeMesh(s, 0)
+	var holes [][]Vec3
+	for _, loop := range m.closedBoundaryLoops() {
+		hole := make([]Vec3, len(loop))
+		for i, h := range loop {
+			hole[i] = m.Vertices[m.HalfEdges[h].Vertex]
+		}
+		holes = append(holes, hole)
+	}
+	return holes
+}
+
+// closedBoundaryLoops returns the boundary loops with at least 3 edges
+// that end where they start.
+func (m *HalfEdgeMesh) closedBoundaryLoops() [][]int {
+	var closed [][]int
+	for _, loop := range m.BoundaryLoops() {
+		if len(loop) >= 3 && m.Target(loop[len(loop)-1]) == m.HalfEdges[loop[0]].Vertex {
+			closed = append(closed, loop)
+		}
+	}
+	return closed
+}
+
+// FillHoles closes every hole with at most maxEdges edges, see Holes, by
+// appending triangles. A maxEdges <= 0 means no limit. The hole is projected
+// onto its best-fit plane and triangulated by ear clipping, with the
+// orientation matching the adjacent triangles. Returns the number of holes
+// filled.
+func (s *Solid) FillHoles(maxEdges int) int {
+	filled := 0
+	for _, hole := range s.Holes() {
+		if maxEdges > 0 && len(hole) > maxEdges {
+			continue
+		}
+		// the new triangles have to use the edges in the opposite direction
+		polygon := make([]Vec3, len(hole))
+		for i, v := range hole {
+			polygon[len(hole)-1-i] = v
+		}
+		for _, vs := range triangulatePolygon(polygon) {
+			t := Triangle{Vertices: vs}
+			t.recalculateNormal()
+			s.Triangles = append(s.Triangles, t)
+		}
+		filled++
+	}
+	return filled
+}
+
+// triangulatePolygon triangulates a simple polygon in 3D space by ear
+// clipping after projecting it onto its best-fit plane. The triangles keep
+// the orientation of the polygon. If the projection is not simple, the
+// result may contain overlapping triangles, but it always consists of
+// len(polygon) - 2 triangles using the polygon's vertices.
+func triangulatePolygon(polygon []Vec3) [][3]Vec3 {
+	points := make([]vec64, len(polygon))
+	for i, p := range polygon {
+		points[i] = toVec64(p)
+	}
+	u, v := planeBasis(newellNormal(points))
+	projected := make([][2]float64, len(points))
+	for i, p := range points {
+		projected[i] = [2]float64{p.dot(u), p.dot(v)}
+	}
+
+	var triangles [][3]Vec3
+	for _, t := range earClipping(projected) {
+		triangles = append(triangles, [3]Vec3{polygon[t[0]], polygon[t[1]], polygon[t[2]]})
+	}
+	return triangles
+}
+
+// newellNormal returns the normal of the best-fit plane of a polygon, using
+// Newell's method. Its direction follows the right hand rule.
+func newellNormal(points []vec64) vec64 {
+	var n vec64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		n[0] += (p[1] - q[1]) * (p[2] + q[2])
+		n[1] += (p[2] - q[2]) * (p[0] + q[0])
+		n[2] += (p[0] - q[0]) * (p[1] + q[1])
+	}
+	return n.unit()
+}
+
+// earClipping triangulates the counter-clockwise 2D polygon given by points,
+// returning the indices of the triangles' vertices, also counter-clockwise.
+// If no proper ear can be found, because the polygon is not simple, the
+// most convex vertex is clipped anyway. Vertices that are collinear with their
+// neighbors within the rounding error of float32 coordinates are no ears, so
+// no slivers are created from vertices lying on an edge.
+func earClipping(points [][2]float64) [][3]int {
+	remaining := make([]int, len(points))
+	for i := range remaining {
+		remaining[i] = i
+	}
+	var scale float64
+	for _, p := range points {
+		scale = math.Max(scale, math.Max(math.Abs(p[0]), math.Abs(p[1])))
+	}
+	rounding := 1e-6 * scale
+	var triangles [][3]int
+	for len(remaining) > 3 {
+		n := len(remaining)
+		ear, best := -1, -1
+		bestCross := 0.0
+		for i := 0; i < n; i++ {
+			a, b, c := points[remaining[(i+n-1)%n]], points[remaining[i]], points[remaining[(i+1)%n]]
+			cross := cross2(a, b, c)
+			if best < 0 || cross > bestCross {
+				best, bestCross = i, cross
+			}
+			if cross <= rounding*(dist2(a, b)+dist2(b, c)) {
+				continue
+			}
+			isEar := true
+			for j := 0; j < n && isEar; j++ {
+				if j == i || j == (i+n-1)%n || j == (i+1)%n {
+					continue
+				}
+				p := points[remaining[j]]
+				if p != a && p != b && p != c && pointInTriangle2(p, a, b, c) {
+					isEar = false
+				}
+			}
+			if isEar {
+				ear = i
+				break
+			}
+		}
+		if ear < 0 {
+			ear = best
+		}
+		triangles = append(triangles, [3]int{remaining[(ear+n-1)%n], remaining[ear], remaining[(ear+1)%n]})
+		remaining = append(remaining[:ear], remaining[ear+1:]...)
+	}
+	if len(remaining) == 3 {
+		triangles = append(triangles, [3]int{remaining[0], remaining[1], remaining[2]})
+	}
+	return triangles
+}
+
+// cross2 returns the z component of (b - a) x (c - b), which is positive if
+// a, b, c make a left turn.
+func cross2(a, b, c [2]float64) float64 {
+	return (b[0]-a[0])*(c[1]-b[1]) - (b[1]-a[1])*(c[0]-b[0])
+}
+
+// dist2 returns the distance between a and b.
+func dist2(a, b [2]float64) float64 {
+	return math.Hypot(b[0]-a[0], b[1]-a[1])
+}
+
+// pointInTriangle2 returns true if p lies within or on the border of the
+// counter-clockwise triangle a, b, c.
+func pointInTriangle2(p, a, b, c [2]float64) bool {
+	return cross2(a, b, p) >= 0 && cross2(b, c, p) >= 0 && cross2(c, a, p) >= 0
+}
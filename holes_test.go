@@ -0,0 +1,89 @@
+package stl
+
+// Tests for hole detection and filling
+
+import (
+	"testing"
+)
+
+func TestHoles(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	if holes := s.Holes(); len(holes) != 0 {
+		t.Errorf("expected no holes, got %v", holes)
+	}
+	// remove the -z face
+	s.Triangles = append(s.Triangles[:8], s.Triangles[10:]...)
+	holes := s.Holes()
+	if len(holes) != 1 || len(holes[0]) != 4 {
+		t.Fatalf("expected one hole with 4 vertices, got %v", holes)
+	}
+	for _, v := range holes[0] {
+		if v[2] != 0 {
+			t.Errorf("unexpected hole vertex %v", v)
+		}
+	}
+}
+
+func TestFillHoles(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = append(s.Triangles[:8], s.Triangles[10:]...)
+	if filled := s.FillHoles(3); filled != 0 {
+		t.Errorf("expected no hole with at most 3 edges, filled %d", filled)
+	}
+	if filled := s.FillHoles(0); filled != 1 {
+		t.Errorf("expected 1 hole filled, got %d", filled)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("filled cube is not valid: %v", errors)
+	}
+	if v := s.Volume(); !almostEqual64(v, 1, 1e-6) {
+		t.Errorf("expected volume 1, got %g", v)
+	}
+}
+
+func TestFillHolesConcave(t *testing.T) {
+	// L-shaped hole in the xy plane, the surface is a pyramid above it
+	outline := []Vec3{{0, 0, 0}, {2, 0, 0}, {2, 1, 0}, {1, 1, 0}, {1, 2, 0}, {0, 2, 0}}
+	apex := Vec3{0.7, 0.7, 1}
+	s := &Solid{}
+	for i := range outline {
+		tri := Triangle{Vertices: [3]Vec3{outline[i], outline[(i+1)%len(outline)], apex}}
+		tri.recalculateNormal()
+		s.Triangles = append(s.Triangles, tri)
+	}
+	if filled := s.FillHoles(0); filled != 1 {
+		t.Fatalf("expected 1 hole filled, got %d", filled)
+	}
+	if len(s.Triangles) != 10 {
+		t.Errorf("expected 4 triangles to fill the hole, got %d", len(s.Triangles)-6)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("filled solid is not valid: %v", errors)
+	}
+	for _, tri := range s.Triangles[6:] {
+		if tri.Normal != (Vec3{0, 0, -1}) {
+			t.Errorf("filling triangle %v is not facing downwards", tri)
+		}
+	}
+}
+
+func TestEarClippingAlmostCollinear(t *testing.T) {
+	// a square with a vertex on its bottom edge, moved slightly outwards by
+	// rounding, which must not be clipped as a sliver
+	points := [][2]float64{{0.5, -1e-9}, {1, 0}, {1, 1}, {0, 1}, {0, 0}}
+	triangles := earClipping(points)
+	if len(triangles) != 3 {
+		t.Fatalf("expected 3 triangles, got %d", len(triangles))
+	}
+	var area float64
+	for _, tr := range triangles {
+		a := cross2(points[tr[0]], points[tr[1]], points[tr[2]]) / 2
+		if a < 0.1 {
+			t.Errorf("triangle %v has area %g", tr, a)
+		}
+		area += a
+	}
+	if !almostEqual64(area, 1, 1e-6) {
+		t.Errorf("expected area 1, got %g", area)
+	}
+}
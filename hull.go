@@ -0,0 +1,344 @@
+package stl
+
+// This file contains the calculation of the convex hull of a solid
+
+import (
+	"math"
+	"sort"
+)
+
+// ConvexHull returns the convex hull of the vertices of s as a closed solid
+// with triangles facing outwards, calculated by the quickhull algorithm.
+// Duplicate vertices, and vertices inside the hull or on its faces, are not
+// used. If all vertices lie in a plane, the result is the flat convex polygon
+// triangulated on both sides, with a volume of 0. If they lie on a line, or
+// s has less than three distinct vertices, the result has no triangles.
+// The result has the name, binary header and format of s.
+func (s *Solid) ConvexHull() *Solid {
+	hull := s.emptyCopy()
+	points := s.distinctVertices()
+	for _, t := range convexHull(points) {
+		hull.appendNonDegenerate(0, points[t[0]].vec3(), points[t[1]].vec3(), points[t[2]].vec3())
+	}
+	return hull
+}
+
+// Convexity returns the ratio of the volume of s to the volume of its convex
+// hull, which is 1 for convex solids, and the smaller the more concave s is.
+// It is 0 if the hull has no volume.
+func (s *Solid) Convexity() float64 {
+	hullVolume := s.ConvexHull().Volume()
+	if hullVolume <= 0 {
+		return 0
+	}
+	return s.Volume() / hullVolume
+}
+
+// distinctVertices returns every distinct vertex of s once, in the order
+// of their first occurrence. -0 and 0 are considered equal.
+func (s *Solid) distinctVertices() []vec64 {
+	seen := make(map[Vec3]bool)
+	var points []vec64
+	for i := range s.Triangles {
+		for _, v := range s.Triangles[i].Vertices {
+			v = withoutNegativeZero(v)
+			if !seen[v] {
+				seen[v] = true
+				points = append(points, toVec64(v))
+			}
+		}
+	}
+	return points
+}
+
+// hullFace is a triangle of the convex hull being built.
+type hullFace struct {
+	v       [3]int
+	normal  vec64 // unit length, pointing outwards
+	offset  float64
+	outside []int // points outside of the face, not yet in the hull
+	deleted bool
+	visited int // last iteration the face was found visible in
+}
+
+// distance returns the signed distance of p from the plane of f, positive
+// outside.
+func (f *hullFace) distance(p vec64) float64 {
+	return f.normal.dot(p) - f.offset
+}
+
+// quickhull holds the state of the quickhull algorithm.
+type quickhull struct {
+	points []vec64
+	eps    float64
+	faces  []hullFace
+
+	// edges maps every directed edge of a face to the face
+	edges map[[2]int]int
+}
+
+// convexHull returns the triangles of the convex hull of points as indices
+// into points, ordered counter-clockwise seen from outside.
+func convexHull(points []vec64) [][3]int {
+	if len(points) < 3 {
+		return nil
+	}
+	q := quickhull{points: points, edges: make(map[[2]int]int)}
+	var scale float64
+	for _, p := range points {
+		for c := 0; c < 3; c++ {
+			scale = math.Max(scale, math.Abs(p[c]))
+		}
+	}
+	q.eps = 1e-10 * scale
+
+	simplex, dim := q.initialSimplex()
+	switch dim {
+	case 2:
+		return q.planarHull(simplex)
+	case 3:
+		q.build(simplex)
+	default:
+		return nil
+	}
+	var triangles [][3]int
+	for i := range q.faces {
+		if !q.faces[i].deleted {
+			triangles = append(triangles, q.faces[i].v)
+		}
+	}
+	return triangles
+}
+
+// initialSimplex returns four points spanning a tetrahedron as large as
+// easily possible, and the dimension of the space spanned by all points,
+// which is less than 3 if they lie on a plane or line.
+func (q *quickhull) initialSimplex() (simplex [4]int, dim int) {
+	// the pair of extreme points along an axis that are farthest apart
+	var extremes [6]int
+	for i, p := range q.points {
+		for c := 0; c < 3; c++ {
+			if p[c] < q.points[extremes[2*c]][c] {
+				extremes[2*c] = i
+			}
+			if p[c] > q.points[extremes[2*c+1]][c] {
+				extremes[2*c+1] = i
+			}
+		}
+	}
+	best := -1.0
+	for _, i := range extremes {
+		for _, j := range extremes {
+			if d := q.points[i].sub(q.points[j]).len(); d > best {
+				best = d
+				simplex[0], simplex[1] = i, j
+			}
+		}
+	}
+	if best <= q.eps {
+		return simplex, 0
+	}
+
+	// the point farthest from their line
+	a := q.points[simplex[0]]
+	dir := q.points[simplex[1]].sub(a).unit()
+	best = -1
+	for i, p := range q.points {
+		if d := p.sub(a).cross(dir).len(); d > best {
+			best = d
+			simplex[2] = i
+		}
+	}
+	if best <= q.eps {
+		return simplex, 1
+	}
+
+	// the point farthest from their plane
+	n := q.points[simplex[1]].sub(a).cross(q.points[simplex[2]].sub(a)).unit()
+	best = -1
+	for i, p := range q.points {
+		if d := math.Abs(n.dot(p.sub(a))); d > best {
+			best = d
+			simplex[3] = i
+		}
+	}
+	if best <= q.eps {
+		return simplex, 2
+	}
+	return simplex, 3
+}
+
+// addFace adds the face a, b, c, which has to be counter-clockwise seen from
+// outside, and returns its index.
+func (q *quickhull) addFace(a, b, c int) int {
+	pa := q.points[a]
+	n := q.points[b].sub(pa).cross(q.points[c].sub(pa)).unit()
+	q.faces = append(q.faces, hullFace{v: [3]int{a, b, c}, normal: n, offset: n.dot(pa), visited: -1})
+	f := len(q.faces) - 1
+	q.edges[[2]int{a, b}] = f
+	q.edges[[2]int{b, c}] = f
+	q.edges[[2]int{c, a}] = f
+	return f
+}
+
+// assign adds point p to the outside set of the first of faces it is more
+// than eps outside of. Points inside all faces are dropped.
+func (q *quickhull) assign(p int, faces []int) {
+	for _, f := range faces {
+		if q.faces[f].distance(q.points[p]) > q.eps {
+			q.faces[f].outside = append(q.faces[f].outside, p)
+			return
+		}
+	}
+}
+
+// build runs the quickhull algorithm, starting with the tetrahedron simplex.
+func (q *quickhull) build(simplex [4]int) {
+	a, b, c, d := simplex[0], simplex[1], simplex[2], simplex[3]
+	pa := q.points[a]
+	if q.points[b].sub(pa).cross(q.points[c].sub(pa)).dot(q.points[d].sub(pa)) > 0 {
+		b, c = c, b // d has to be below a, b, c
+	}
+	initial := []int{q.addFace(a, b, c), q.addFace(a, d, b), q.addFace(b, d, c), q.addFace(c, d, a)}
+	for p := range q.points {
+		if p != a && p != b && p != c && p != d {
+			q.assign(p, initial)
+		}
+	}
+
+	pending := append([]int(nil), initial...)
+	for iteration := 0; len(pending) > 0; iteration++ {
+		f := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if q.faces[f].deleted || len(q.faces[f].outside) == 0 {
+			continue
+		}
+
+		// the outside point farthest from the face is added to the hull
+		eye, eyeDist := -1, 0.0
+		for _, p := range q.faces[f].outside {
+			if d := q.faces[f].distance(q.points[p]); d > eyeDist {
+				eye, eyeDist = p, d
+			}
+		}
+		pe := q.points[eye]
+
+		// the faces visible from eye, connected to f
+		visible := []int{f}
+		q.faces[f].visited = iteration
+		for i := 0; i < len(visible); i++ {
+			v := q.faces[visible[i]].v
+			for e := 0; e < 3; e++ {
+				g := q.edges[[2]int{v[(e+1)%3], v[e]}]
+				if q.faces[g].visited != iteration && q.faces[g].distance(pe) > q.eps {
+					q.faces[g].visited = iteration
+					visible = append(visible, g)
+				}
+			}
+		}
+
+		// the edges between visible and other faces form the horizon, which
+		// is connected to eye by new faces
+		var horizon [][2]int
+		var orphans []int
+		for _, g := range visible {
+			v := q.faces[g].v
+			for e := 0; e < 3; e++ {
+				edge := [2]int{v[e], v[(e+1)%3]}
+				if q.faces[q.edges[[2]int{edge[1], edge[0]}]].visited != iteration {
+					horizon = append(horizon, edge)
+				}
+			}
+		}
+		for _, g := range visible {
+			v := q.faces[g].v
+			for e := 0; e < 3; e++ {
+				delete(q.edges, [2]int{v[e], v[(e+1)%3]})
+			}
+			for _, p := range q.faces[g].outside {
+				if p != eye {
+					orphans = append(orphans, p)
+				}
+			}
+			q.faces[g].outside = nil
+			q.faces[g].deleted = true
+		}
+		newFaces := make([]int, len(horizon))
+		for i, edge := range horizon {
+			newFaces[i] = q.addFace(edge[0], edge[1], eye)
+		}
+		for _, p := range orphans {
+			q.assign(p, newFaces)
+		}
+		pending = append(pending, newFaces...)
+	}
+}
+
+// planarHull returns the convex polygon around the points, which lie in the
+// plane through the first three points of simplex, triangulated on both
+// sides.
+func (q *quickhull) planarHull(simplex [4]int) [][3]int {
+	a := q.points[simplex[0]]
+	n := q.points[simplex[1]].sub(a).cross(q.points[simplex[2]].sub(a)).unit()
+	u, v := planeBasis(n)
+	projected := make([][2]float64, len(q.points))
+	for i, p := range q.points {
+		projected[i] = [2]float64{p.dot(u), p.dot(v)}
+	}
+	polygon := convexHull2(projected, q.eps)
+	if len(polygon) < 3 {
+		return nil
+	}
+
+	// the fans on both sides start at different vertices, so that no
+	// diagonal is shared by more than two triangles
+	var triangles [][3]int
+	k := len(polygon)
+	for i := 1; i+1 < k; i++ {
+		triangles = append(triangles,
+			[3]int{polygon[0], polygon[i], polygon[i+1]},
+			[3]int{polygon[1], polygon[(i+2)%k], polygon[i+1]})
+	}
+	return triangles
+}
+
+// convexHull2 returns the indices of the points forming the convex hull of
+// points, counter-clockwise, using Andrew's monotone chain algorithm. Points
+// less than about eps away from the edges are dropped.
+func convexHull2(points [][2]float64, eps float64) []int {
+	order := make([]int, len(points))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		pi, pj := points[order[i]], points[order[j]]
+		if pi[0] != pj[0] {
+			return pi[0] < pj[0]
+		}
+		return pi[1] < pj[1]
+	})
+	turnsLeft := func(o, a, b int) bool {
+		po, pa, pb := points[o], points[a], points[b]
+		return cross2(po, pa, pb) > eps*(math.Abs(pa[0]-po[0])+math.Abs(pa[1]-po[1])+math.Abs(pb[0]-po[0])+math.Abs(pb[1]-po[1]))
+	}
+	var polygon []int
+	for pass := 0; pass < 2 && len(order) > 0; pass++ {
+		start := len(polygon)
+		for _, p := range order {
+			for len(polygon) >= start+2 && !turnsLeft(polygon[len(polygon)-2], polygon[len(polygon)-1], p) {
+				polygon = polygon[:len(polygon)-1]
+			}
+			polygon = append(polygon, p)
+		}
+		polygon = polygon[:len(polygon)-1]
+		reverseInts(order)
+	}
+	return polygon
+}
+
+// reverseInts reverses the order of the elements of s.
+func reverseInts(s []int) {
+	for i, j := 0, len(s)-1; i < j; i, j = i+1, j-1 {
+		s[i], s[j] = s[j], s[i]
+	}
+}
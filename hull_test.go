@@ -0,0 +1,128 @@
+package stl
+
+// Tests for convex hulls
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// checkHull tests that hull is a closed convex solid facing outwards that
+// contains all vertices of s.
+func checkHull(t *testing.T, s, hull *Solid) {
+	t.Helper()
+	if errors := hull.Validate(); len(errors) != 0 {
+		t.Fatalf("hull is not valid: %v", errors)
+	}
+	scale := toVec64(hull.Measure().Len).len()
+	for i := range hull.Triangles {
+		tr := hull.Triangles[i].triangle64()
+		n := tr.normal().unit()
+		for j := range s.Triangles {
+			for _, v := range s.Triangles[j].Vertices {
+				if d := n.dot(toVec64(v).sub(tr[0])); d > 1e-9*scale {
+					t.Fatalf("vertex %v is %g outside of hull triangle %d", v, d, i)
+				}
+			}
+		}
+	}
+}
+
+func TestConvexHullCube(t *testing.T) {
+	cube := makeTestCube(Vec3{1, 2, 3}, 2)
+	s := cube.ConvexHull()
+	checkHull(t, cube, s)
+	if len(s.Triangles) != 12 || !almostEqual64(s.Volume(), 8, 1e-9) {
+		t.Errorf("expected 12 triangles with volume 8, got %d with %g", len(s.Triangles), s.Volume())
+	}
+	if s.Name != cube.Name || s.IsAscii != cube.IsAscii {
+		t.Errorf("expected name and format of the cube, got %q %v", s.Name, s.IsAscii)
+	}
+
+	// duplicates, inner points, and points on faces and edges are dropped
+	points := Merge(cube, makeTestCube(Vec3{2, 3, 4}, 1), makeTestCube(Vec3{1, 2, 3}, 1), cube)
+	s = points.ConvexHull()
+	checkHull(t, points, s)
+	if len(s.Triangles) != 12 || !almostEqual64(s.Volume(), 8, 1e-9) {
+		t.Errorf("expected 12 triangles with volume 8, got %d with %g", len(s.Triangles), s.Volume())
+	}
+	if c := cube.Convexity(); !almostEqual64(c, 1, 1e-9) {
+		t.Errorf("expected convexity 1, got %g", c)
+	}
+}
+
+func TestConvexHullTorus(t *testing.T) {
+	torus := makeTestTorus(3, 1, 64, 32)
+	s := torus.ConvexHull()
+	checkHull(t, torus, s)
+	// the hull of a torus is a cylinder with a half torus around it
+	R, r := 3.0, 1.0
+	want := Pi*R*R*2*r + Pi*Pi*R*r*r + 4.0/3*Pi*r*r*r
+	if v := s.Volume(); !almostEqual64(v, want, 0.01*want) {
+		t.Errorf("expected hull volume about %g, got %g", want, v)
+	}
+	wantConvexity := 2 * Pi * Pi * R * r * r / want
+	if c := torus.Convexity(); !almostEqual64(c, wantConvexity, 0.01) {
+		t.Errorf("expected convexity about %g, got %g", wantConvexity, c)
+	}
+}
+
+func TestConvexHullSphere(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	s := &Solid{}
+	for i := 0; i < 2000; i++ {
+		var tr Triangle
+		for v := 0; v < 3; v++ {
+			p := vec64{rnd.NormFloat64(), rnd.NormFloat64(), rnd.NormFloat64()}.unit()
+			if rnd.Intn(2) == 0 {
+				p = p.scale(0.9 * rnd.Float64())
+			}
+			tr.Vertices[v] = p.vec3()
+		}
+		s.Triangles = append(s.Triangles, tr)
+	}
+	hull := s.ConvexHull()
+	checkHull(t, s, hull)
+	for _, tr := range hull.Triangles {
+		for _, v := range tr.Vertices {
+			if l := toVec64(v).len(); math.Abs(l-1) > 1e-6 {
+				t.Fatalf("inner point %v with radius %g on hull", v, l)
+			}
+		}
+	}
+}
+
+func TestConvexHullDegenerate(t *testing.T) {
+	flat := &Solid{Triangles: []Triangle{
+		{Vertices: [3]Vec3{{0, 0, 1}, {2, 0, 1}, {2, 2, 1}}},
+		{Vertices: [3]Vec3{{0, 0, 1}, {1, 1, 1}, {0, 2, 1}}},
+		{Vertices: [3]Vec3{{1, 0, 1}, {1, 2, 1}, {0, 1, 1}}},
+	}}
+	s := flat.ConvexHull()
+	if errors := s.Validate(); len(errors) != 0 {
+		for i, e := range errors {
+			t.Errorf("flat hull triangle %d is not valid: %+v", i, e)
+		}
+	}
+	if len(s.Triangles) != 4 || s.Volume() != 0 || !almostEqual64(s.SurfaceArea(), 8, 1e-9) {
+		t.Errorf("expected 4 triangles covering both sides of a square, got %v", s.Triangles)
+	}
+
+	disc := &Solid{}
+	for i := 0; i < 12; i++ {
+		a, b := TwoPi*float64(i)/12, TwoPi*float64(i+1)/12
+		disc.appendNonDegenerate(0, Vec3{0, 0, 0}, Vec3{float32(math.Cos(a)), float32(math.Sin(a)), 0}, Vec3{float32(math.Cos(b)), float32(math.Sin(b)), 0})
+	}
+	if s := disc.ConvexHull(); len(s.Triangles) != 20 || len(s.Validate()) != 0 {
+		t.Errorf("expected 20 valid triangles covering both sides of a 12-gon, got %v", s.Triangles)
+	}
+
+	line := &Solid{Triangles: []Triangle{{Vertices: [3]Vec3{{0, 0, 0}, {1, 1, 1}, {3, 3, 3}}}}}
+	if s := line.ConvexHull(); len(s.Triangles) != 0 {
+		t.Errorf("expected no triangles for points on a line, got %v", s.Triangles)
+	}
+	if s := (&Solid{}).ConvexHull(); len(s.Triangles) != 0 {
+		t.Errorf("expected no triangles for no points, got %v", s.Triangles)
+	}
+}
@@ -0,0 +1,139 @@
+package stl
+
+// This file defines the IndexedMesh data type, storing every vertex
+// only once
+
+import (
+	"sort"
+)
+
+// IndexedMesh is an alternative representation of a Solid, where every
+// vertex is stored only once, and triangles, called faces here, refer
+// to their vertices by index. This makes it cheap to find out which
+// faces share a vertex or an edge.
+type IndexedMesh struct {
+	// Vertices contains every distinct vertex once.
+	Vertices []Vec3
+
+	// Faces contains the indices into Vertices of every face's vertices,
+	// in the same order as in Triangle.Vertices.
+	Faces [][3]uint32
+
+	// Normals contains the normal vector of every face.
+	Normals []Vec3
+
+	// Attributes contains the attributes of every face.
+	Attributes []uint16
+}
+
+// NewIndexedMesh creates an IndexedMesh from the triangles in s, with one
+// face per triangle in the same order. Vertices that are exactly equal
+// are welded into one. If weldTolerance is > 0, vertices are also welded if
+// no coordinate differs more than weldTolerance, like in Vec3.AlmostEqual.
+// In that case, the first vertex encountered determines the position.
+func NewIndexedMesh(s *Solid, weldTolerance float32) *IndexedMesh {
+	n := len(s.Triangles)
+	m := IndexedMesh{
+		Faces:      make([][3]uint32, n),
+		Normals:    make([]Vec3, n),
+		Attributes: make([]uint16, n),
+	}
+	w := newVertexWelder(weldTolerance, n/2+3)
+	for i := range s.Triangles {
+		t := &s.Triangles[i]
+		for v := 0; v < 3; v++ {
+			m.Faces[i][v] = w.index(t.Vertices[v])
+		}
+		m.Normals[i] = t.Normal
+		m.Attributes[i] = t.Attributes
+	}
+	m.Vertices = w.vertices
+	return &m
+}
+
+// Solid converts m back into a Solid with one triangle per face, in the same
+// order. Name and BinaryHeader remain empty.
+func (m *IndexedMesh) Solid() *Solid {
+	s := Solid{Triangles: make([]Triangle, len(m.Faces))}
+	for i, f := range m.Faces {
+		t := &s.Triangles[i]
+		for v := 0; v < 3; v++ {
+			t.Vertices[v] = m.Vertices[f[v]]
+		}
+		t.Normal = m.Normals[i]
+		t.Attributes = m.Attributes[i]
+	}
+	return &s
+}
+
+// Weld merges all vertices where no coordinate differs more than tolerance,
+// like in Vec3.AlmostEqual, and updates Faces accordingly. The first vertex
+// in Vertices determines the position of the merged vertex. Faces can become
+// degenerate by this, i.e. refer to the same vertex more than once.
+// Returns the number of vertices removed.
+func (m *IndexedMesh) Weld(tolerance float32) int {
+	w := newVertexWelder(tolerance, len(m.Vertices))
+	newIndex := make([]uint32, len(m.Vertices))
+	for i, v := range m.Vertices {
+		newIndex[i] = w.index(v)
+	}
+	for i := range m.Faces {
+		for v := 0; v < 3; v++ {
+			m.Faces[i][v] = newIndex[m.Faces[i][v]]
+		}
+	}
+	removed := len(m.Vertices) - len(w.vertices)
+	m.Vertices = w.vertices
+	return removed
+}
+
+// VertexFaces returns the indices of the faces using a vertex, for every
+// vertex in Vertices. A face referring to the same vertex twice is only
+// listed once.
+func (m *IndexedMesh) VertexFaces() [][]uint32 {
+	vertexFaces := make([][]uint32, len(m.Vertices))
+	for i, f := range m.Faces {
+		for v := 0; v < 3; v++ {
+			if (v > 0 && f[v] == f[0]) || (v == 2 && f[2] == f[1]) {
+				continue
+			}
+			vertexFaces[f[v]] = append(vertexFaces[f[v]], uint32(i))
+		}
+	}
+	return vertexFaces
+}
+
+// VertexNeighbors returns the indices of the vertices connected to a vertex
+// by an edge, for every vertex in Vertices. The indices are sorted ascending.
+func (m *IndexedMesh) VertexNeighbors() [][]uint32 {
+	neighbors := make([][]uint32, len(m.Vertices))
+	for _, f := range m.Faces {
+		for v := 0; v < 3; v++ {
+			a, b := f[v], f[(v+1)%3]
+			if a != b {
+				neighbors[a] = append(neighbors[a], b)
+				neighbors[b] = append(neighbors[b], a)
+			}
+		}
+	}
+	for i, nb := range neighbors {
+		neighbors[i] = sortedUniqueUint32(nb)
+	}
+	return neighbors
+}
+
+// sortedUniqueUint32 sorts s ascending and removes duplicates in place.
+func sortedUniqueUint32(s []uint32) []uint32 {
+	if len(s) < 2 {
+		return s
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	n := 1
+	for i := 1; i < len(s); i++ {
+		if s[i] != s[n-1] {
+			s[n] = s[i]
+			n++
+		}
+	}
+	return s[:n]
+}
@@ -0,0 +1,61 @@
+package stl
+
+// Tests for the IndexedMesh data type
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewIndexedMesh(t *testing.T) {
+	s := makeTestSolid()
+	m := NewIndexedMesh(s, 0)
+	expectedVertices := []Vec3{{0, 0, 0}, {0, 1, 0}, {1, 0, 0}, {0, 0, 1}}
+	if !reflect.DeepEqual(m.Vertices, expectedVertices) {
+		t.Errorf("Vertices: expected %v, got %v", expectedVertices, m.Vertices)
+	}
+	expectedFaces := [][3]uint32{{0, 1, 2}, {0, 2, 3}, {3, 2, 1}, {0, 3, 1}}
+	if !reflect.DeepEqual(m.Faces, expectedFaces) {
+		t.Errorf("Faces: expected %v, got %v", expectedFaces, m.Faces)
+	}
+
+	back := m.Solid()
+	back.Name = s.Name
+	back.IsAscii = s.IsAscii
+	if !s.sameOrderAlmostEqual(back) {
+		t.Error("Not equal after round trip")
+		t.Log("Expected:\n", s)
+		t.Log("Found:\n", back)
+	}
+}
+
+func TestIndexedMeshWeld(t *testing.T) {
+	s := makeTestSolid()
+	s.Triangles[2].Vertices[0] = Vec3{0, 0, 1.0001}
+	exact := NewIndexedMesh(s, 0)
+	if len(exact.Vertices) != 5 {
+		t.Errorf("expected 5 vertices without tolerance, got %d", len(exact.Vertices))
+	}
+	tolerant := NewIndexedMesh(s, 0.001)
+	if len(tolerant.Vertices) != 4 {
+		t.Errorf("expected 4 vertices with tolerance, got %d", len(tolerant.Vertices))
+	}
+	if removed := exact.Weld(0.001); removed != 1 {
+		t.Errorf("expected Weld to remove 1 vertex, removed %d", removed)
+	}
+	if !reflect.DeepEqual(exact.Faces, tolerant.Faces) {
+		t.Errorf("Weld faces %v differ from welding while creating %v", exact.Faces, tolerant.Faces)
+	}
+}
+
+func TestIndexedMeshAdjacency(t *testing.T) {
+	m := NewIndexedMesh(makeTestSolid(), 0)
+	expectedFaces := [][]uint32{{0, 1, 3}, {0, 2, 3}, {0, 1, 2}, {1, 2, 3}}
+	if vf := m.VertexFaces(); !reflect.DeepEqual(vf, expectedFaces) {
+		t.Errorf("VertexFaces: expected %v, got %v", expectedFaces, vf)
+	}
+	expectedNeighbors := [][]uint32{{1, 2, 3}, {0, 2, 3}, {0, 1, 3}, {0, 1, 2}}
+	if vn := m.VertexNeighbors(); !reflect.DeepEqual(vn, expectedNeighbors) {
+		t.Errorf("VertexNeighbors: expected %v, got %v", expectedNeighbors, vn)
+	}
+}
@@ -0,0 +1,164 @@
+package stl
+
+// This file contains calculations of volume, surface area, and centre of mass
+
+// Volume returns the volume enclosed by the solid, using the divergence
+// theorem. This is only meaningful for closed solids. The result is
+// negative if the triangles are oriented inside-out, i.e. their vertices
+// are ordered clockwise when looking at them from outside.
+func (s *Solid) Volume() float64 {
+	if len(s.Triangles) == 0 {
+		return 0
+	}
+	ref := toVec64(s.Triangles[0].Vertices[0])
+	var sixVolume float64
+	for i := range s.Triangles {
+		a, b, c := s.Triangles[i].relativeVertices(ref)
+		sixVolume += a.dot(b.cross(c))
+	}
+	return sixVolume / 6
+}
+
+// SurfaceArea returns the sum of the areas of all triangles.
+func (s *Solid) SurfaceArea() float64 {
+	var area float64
+	for i := range s.Triangles {
+		area += s.Triangles[i].Area()
+	}
+	return area
+}
+
+// Centroid returns the centre of mass of the volume enclosed by the solid,
+// assuming uniform density. If the volume is 0, e.g. because the solid
+// is a single flat surface, the centre of mass of the surface is returned
+// instead, and if that has no area either, the average of all vertices.
+func (s *Solid) Centroid() Vec3 {
+	return s.centroid().vec3()
+}
+
+func (s *Solid) centroid() vec64 {
+	if len(s.Triangles) == 0 {
+		return vec64{}
+	}
+	ref := toVec64(s.Triangles[0].Vertices[0])
+
+	// Each triangle forms a tetrahedron with ref, with the centroid
+	// at (ref + a + b + c) / 4, relative to ref just (a + b + c) / 4.
+	var sixVolume float64
+	var volumeSum vec64
+	for i := range s.Triangles {
+		a, b, c := s.Triangles[i].relativeVertices(ref)
+		det := a.dot(b.cross(c))
+		sixVolume += det
+		volumeSum = volumeSum.add(a.add(b).add(c).scale(det))
+	}
+	if sixVolume != 0 {
+		return ref.add(volumeSum.scale(1 / (4 * sixVolume)))
+	}
+
+	var area float64
+	var areaSum vec64
+	for i := range s.Triangles {
+		a, b, c := s.Triangles[i].relativeVertices(ref)
+		triangleArea := s.Triangles[i].Area()
+		area += triangleArea
+		areaSum = areaSum.add(a.add(b).add(c).scale(triangleArea))
+	}
+	if area != 0 {
+		return ref.add(areaSum.scale(1 / (3 * area)))
+	}
+
+	var vertexSum vec64
+	for i := range s.Triangles {
+		a, b, c := s.Triangles[i].relativeVertices(ref)
+		vertexSum = vertexSum.add(a.add(b).add(c))
+	}
+	return ref.add(vertexSum.scale(1 / float64(3*len(s.Triangles))))
+}
+
+// relativeVertices returns the vertices of t in double precision, relative
+// to ref. Using a reference point close to the solid instead of the origin
+// limits cancellation errors.
+func (t *Triangle) relativeVertices(ref vec64) (a, b, c vec64) {
+	return toVec64(t.Vertices[0]).sub(ref),
+		toVec64(t.Vertices[1]).sub(ref),
+		toVec64(t.Vertices[2]).sub(ref)
+}
+
+// secondMoments returns the integral of r*r^T over the enclosed volume, with
+// r = x - ref, as used for inertia tensors.
+func (s *Solid) secondMoments(ref vec64) Mat3 {
+	var c Mat3
+	for i := range s.Triangles {
+		a, b, cc := s.Triangles[i].relativeVertices(ref)
+		// For a tetrahedron with vertices 0, a, b, c, the integral is
+		// det/120 * (a*a^T + b*b^T + c*c^T + sum*sum^T), sum = a+b+c.
+		det := a.dot(b.cross(cc))
+		sum := a.add(b).add(cc)
+		for row := 0; row < 3; row++ {
+			for col := 0; col < 3; col++ {
+				c[row][col] += det / 120 * (a[row]*a[col] + b[row]*b[col] + cc[row]*cc[col] + sum[row]*sum[col])
+			}
+		}
+	}
+	return c
+}
+
+// inertiaTensorAt calculates the inertia tensor about p for uniform density.
+func (s *Solid) inertiaTensorAt(p vec64, density float64) Mat3 {
+	c := s.secondMoments(p)
+	trace := c[0][0] + c[1][1] + c[2][2]
+	var inertia Mat3
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			inertia[row][col] = -density * c[row][col]
+		}
+		inertia[row][row] += density * trace
+	}
+	return inertia
+}
+
+// InertiaTensor returns the inertia tensor of the enclosed volume about its
+// centroid, for the given uniform density in mass per cubic unit. Like Volume,
+// this is only meaningful for closed solids with outward orientation.
+func (s *Solid) InertiaTensor(density float64) Mat3 {
+	return s.inertiaTensorAt(s.centroid(), density)
+}
+
+// InertiaTensorAt returns the inertia tensor of the enclosed volume about the
+// point p, for the given uniform density in mass per cubic unit.
+func (s *Solid) InertiaTensorAt(p Vec3, density float64) Mat3 {
+	return s.inertiaTensorAt(toVec64(p), density)
+}
+
+// PrincipalAxes returns the principal moments of inertia about the centroid,
+// for the given uniform density, sorted ascending, and the corresponding
+// principal axes as unit vectors. The axes form a right-handed system, so
+// the first one is the axis the solid is easiest to spin around.
+func (s *Solid) PrincipalAxes(density float64) (moments [3]float64, axes [3]Vec3) {
+	inertia := s.InertiaTensor(density)
+	values, vectors := inertia.symmetricEigen()
+	for i := range vectors {
+		axes[i] = vectors[i].vec3()
+	}
+	return values, axes
+}
+
+// PrincipalAxesMatrix calculates a 4x4 matrix that rotates the solid around
+// its centroid, so that its principal axes, as returned by PrincipalAxes, are
+// aligned with the x, y, and z axis. The result is written into *alignMatrix,
+// and can be applied using Solid.Transform.
+func (s *Solid) PrincipalAxesMatrix(alignMatrix *Mat4) {
+	c := s.centroid()
+	inertia := s.inertiaTensorAt(c, 1)
+	_, axes := inertia.symmetricEigen()
+	// The rows of the rotation are the axes, so axes[i] is mapped to the
+	// i-th unit vector. The centroid stays where it is.
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			alignMatrix[row][col] = axes[row][col]
+		}
+		alignMatrix[row][3] = c[row] - axes[row].dot(c)
+	}
+	alignMatrix[3] = Vec4{0, 0, 0, 1}
+}
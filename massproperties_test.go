@@ -0,0 +1,120 @@
+package stl
+
+// Tests for volume, surface area, and centre of mass
+
+import (
+	"testing"
+)
+
+func TestVolume(t *testing.T) {
+	cube := makeTestCube(Vec3{10, -20, 30}, 2)
+	if v := cube.Volume(); !almostEqual64(v, 8, 1e-9) {
+		t.Errorf("cube volume: expected 8, got %g", v)
+	}
+	tetrahedron := makeTestSolid()
+	if v := tetrahedron.Volume(); !almostEqual64(v, 1.0/6, 1e-9) {
+		t.Errorf("tetrahedron volume: expected 1/6, got %g", v)
+	}
+
+	// inside-out
+	for i := range cube.Triangles {
+		vs := &cube.Triangles[i].Vertices
+		vs[1], vs[2] = vs[2], vs[1]
+	}
+	if v := cube.Volume(); !almostEqual64(v, -8, 1e-9) {
+		t.Errorf("inverted cube volume: expected -8, got %g", v)
+	}
+}
+
+func TestSurfaceArea(t *testing.T) {
+	cube := makeTestCube(Vec3{10, -20, 30}, 2)
+	if a := cube.SurfaceArea(); !almostEqual64(a, 24, 1e-9) {
+		t.Errorf("cube surface area: expected 24, got %g", a)
+	}
+	tri := Triangle{Vertices: [3]Vec3{{0, 0, 0}, {3, 0, 0}, {0, 4, 0}}}
+	if a := tri.Area(); a != 6 {
+		t.Errorf("triangle area: expected 6, got %g", a)
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	cube := makeTestCube(Vec3{10, -20, 30}, 2)
+	if c := cube.Centroid(); !c.AlmostEqual(Vec3{11, -19, 31}, 1e-5) {
+		t.Errorf("cube centroid: expected [11 -19 31], got %v", c)
+	}
+	tetrahedron := makeTestSolid()
+	if c := tetrahedron.Centroid(); !c.AlmostEqual(Vec3{0.25, 0.25, 0.25}, 1e-6) {
+		t.Errorf("tetrahedron centroid: expected [0.25 0.25 0.25], got %v", c)
+	}
+
+	// open surface without volume
+	flat := &Solid{Triangles: cube.Triangles[8:10]}
+	if c := flat.Centroid(); !c.AlmostEqual(Vec3{11, -19, 30}, 1e-5) {
+		t.Errorf("bottom face centroid: expected [11 -19 30], got %v", c)
+	}
+}
+
+func TestInertiaTensor(t *testing.T) {
+	cube := makeTestCube(Vec3{10, -20, 30}, 2)
+	// mass 8, moment about the centre m * (a^2 + a^2) / 12
+	expected := Mat3{{16.0 / 3, 0, 0}, {0, 16.0 / 3, 0}, {0, 0, 16.0 / 3}}
+	if inertia := cube.InertiaTensor(1); !mat3AlmostEqual(inertia, expected, 1e-6) {
+		t.Errorf("cube inertia tensor: expected %v, got %v", expected, inertia)
+	}
+	// parallel axis theorem with offset d = [1 1 1]: I + m * (d^2 * E - d * d^T)
+	expected = Mat3{{16.0/3 + 16, -8, -8}, {-8, 16.0/3 + 16, -8}, {-8, -8, 16.0/3 + 16}}
+	if inertia := cube.InertiaTensorAt(Vec3{10, -20, 30}, 1); !mat3AlmostEqual(inertia, expected, 1e-6) {
+		t.Errorf("cube inertia tensor at corner: expected %v, got %v", expected, inertia)
+	}
+	expected = Mat3{{32.0 / 3, 0, 0}, {0, 32.0 / 3, 0}, {0, 0, 32.0 / 3}}
+	if inertia := cube.InertiaTensor(2); !mat3AlmostEqual(inertia, expected, 1e-6) {
+		t.Errorf("cube inertia tensor with density 2: expected %v, got %v", expected, inertia)
+	}
+}
+
+func TestPrincipalAxes(t *testing.T) {
+	box := makeTestCube(Vec3{0, 0, 0}, 1)
+	box.Stretch(Vec3{1, 2, 4})
+	// moments m * (b^2 + c^2) / 12 with m = 8
+	expectedMoments := [3]float64{8 * 5.0 / 12, 8 * 17.0 / 12, 8 * 20.0 / 12}
+	moments, axes := box.PrincipalAxes(1)
+	for i := range moments {
+		if !almostEqual64(moments[i], expectedMoments[i], 1e-6) {
+			t.Errorf("moments: expected %v, got %v", expectedMoments, moments)
+			break
+		}
+	}
+	expectedAxes := [3]Vec3{{0, 0, 1}, {0, 1, 0}, {1, 0, 0}}
+	for i := range axes {
+		if !axes[i].AlmostEqual(expectedAxes[i], 1e-6) && !axes[i].AlmostEqual(expectedAxes[i].MultScalar(-1), 1e-6) {
+			t.Errorf("axes: expected %v, got %v", expectedAxes, axes)
+			break
+		}
+	}
+	if det := axes[0].Cross(axes[1]).Dot(axes[2]); !almostEqual64(det, 1, 1e-6) {
+		t.Errorf("axes are not right-handed: %v", axes)
+	}
+
+	box.Rotate(Vec3{3, 1, 2}, Vec3{1, 2, 3}, 0.7)
+	var alignMatrix Mat4
+	box.PrincipalAxesMatrix(&alignMatrix)
+	centroid := box.Centroid()
+	box.Transform(&alignMatrix)
+	if l := box.Measure().Len; !l.AlmostEqual(Vec3{4, 2, 1}, 1e-4) {
+		t.Errorf("size after alignment: expected [4 2 1], got %v", l)
+	}
+	if c := box.Centroid(); !c.AlmostEqual(centroid, 1e-4) {
+		t.Errorf("centroid moved by alignment from %v to %v", centroid, c)
+	}
+}
+
+func mat3AlmostEqual(a, b Mat3, tol float64) bool {
+	for row := 0; row < 3; row++ {
+		for col := 0; col < 3; col++ {
+			if !almostEqual64(a[row][col], b[row][col], tol) {
+				return false
+			}
+		}
+	}
+	return true
+}
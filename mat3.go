@@ -0,0 +1,72 @@
+package stl
+
+// This file contains a 3x3 matrix implementation used for mass properties
+
+import (
+	"math"
+)
+
+// Mat3 represents a 3x3 matrix of float64, e.g. an inertia tensor.
+// Accessing matrix elements goes like this:
+//
+//	matrix[row][column]
+type Mat3 [3][3]float64
+
+// symmetricEigen calculates the eigenvalues and eigenvectors of m, which has
+// to be symmetric, using the cyclic Jacobi method. The eigenvalues are sorted
+// ascending, the eigenvectors have length 1 and form a right-handed system.
+func (m *Mat3) symmetricEigen() (values [3]float64, vectors [3]vec64) {
+	a := *m
+	v := Mat3{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	for sweep := 0; sweep < 50; sweep++ {
+		offDiagonal := math.Abs(a[0][1]) + math.Abs(a[0][2]) + math.Abs(a[1][2])
+		if offDiagonal == 0 {
+			break
+		}
+		for p := 0; p < 2; p++ {
+			for q := p + 1; q < 3; q++ {
+				if a[p][q] == 0 {
+					continue
+				}
+				// rotation angle zeroing a[p][q]
+				theta := (a[q][q] - a[p][p]) / (2 * a[p][q])
+				t := 1 / (math.Abs(theta) + math.Sqrt(theta*theta+1))
+				if theta < 0 {
+					t = -t
+				}
+				c := 1 / math.Sqrt(t*t+1)
+				s := t * c
+				for k := 0; k < 3; k++ {
+					akp, akq := a[k][p], a[k][q]
+					a[k][p] = c*akp - s*akq
+					a[k][q] = s*akp + c*akq
+				}
+				for k := 0; k < 3; k++ {
+					apk, aqk := a[p][k], a[q][k]
+					a[p][k] = c*apk - s*aqk
+					a[q][k] = s*apk + c*aqk
+				}
+				for k := 0; k < 3; k++ {
+					vkp, vkq := v[k][p], v[k][q]
+					v[k][p] = c*vkp - s*vkq
+					v[k][q] = s*vkp + c*vkq
+				}
+			}
+		}
+	}
+
+	order := [3]int{0, 1, 2}
+	for i := 0; i < 2; i++ {
+		for j := i + 1; j < 3; j++ {
+			if a[order[j]][order[j]] < a[order[i]][order[i]] {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+	}
+	for i, col := range order {
+		values[i] = a[col][col]
+		vectors[i] = vec64{v[0][col], v[1][col], v[2][col]}.unit()
+	}
+	vectors[2] = vectors[0].cross(vectors[1])
+	return
+}
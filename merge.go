@@ -0,0 +1,163 @@
+package stl
+
+// This file contains functions to combine several solids into one
+
+// MergeNaming determines the name and binary header of a merged solid.
+type MergeNaming int
+
+const (
+	// MergeNameFirst uses the name and binary header of the first solid.
+	MergeNameFirst MergeNaming = iota
+
+	// MergeNameJoin joins the non-empty names of all solids with "+", and
+	// uses the binary header of the first solid.
+	MergeNameJoin
+
+	// MergeNameCustom uses MergeOptions.Name and MergeOptions.BinaryHeader.
+	MergeNameCustom
+)
+
+// MergeOptions control MergeWithOptions.
+type MergeOptions struct {
+	// Naming determines the name and binary header of the result.
+	Naming MergeNaming
+
+	// Name is the name of the result if Naming is MergeNameCustom.
+	Name string
+
+	// BinaryHeader is the binary header of the result if Naming is
+	// MergeNameCustom.
+	BinaryHeader []byte
+
+	// TagSources overwrites the attributes of every triangle with the index
+	// of the solid it was taken from, so its origin can be recovered later.
+	TagSources bool
+
+	// RemoveInternalFaces removes pairs of triangles using the same vertices
+	// in opposite order, as they occur where two closed parts touch each
+	// other with coincident faces. Only triangles with matching vertices are
+	// removed, the triangulations of the touching faces have to be equal.
+	RemoveInternalFaces bool
+
+	// RemoveDuplicates removes triangles using the same vertices in the same
+	// order as an earlier triangle.
+	RemoveDuplicates bool
+
+	// WeldTolerance is the maximum difference per coordinate up to which
+	// vertices are considered equal when looking for internal or duplicate
+	// triangles, like in NewIndexedMesh. The vertices themselves are not changed.
+	WeldTolerance float32
+}
+
+// Merge combines the triangles of all solids into a new solid, in the given
+// order, with the name, binary header and format of the first solid. Nil
+// solids are skipped. See MergeWithOptions for more control.
+func Merge(solids ...*Solid) *Solid {
+	return MergeWithOptions(MergeOptions{}, solids...)
+}
+
+// MergeWithOptions combines the triangles of all solids into a new solid
+// like Merge, applying options. The format of the result is always taken
+// from the first solid.
+func MergeWithOptions(options MergeOptions, solids ...*Solid) *Solid {
+	merged := &Solid{}
+	first := true
+	count := 0
+	for _, s := range solids {
+		if s == nil {
+			continue
+		}
+		if first {
+			merged.Name = s.Name
+			merged.BinaryHeader = s.BinaryHeader
+			merged.IsAscii = s.IsAscii
+			first = false
+		} else if options.Naming == MergeNameJoin && s.Name != "" {
+			if merged.Name != "" {
+				merged.Name += "+"
+			}
+			merged.Name += s.Name
+		}
+		count += len(s.Triangles)
+	}
+	if options.Naming == MergeNameCustom {
+		merged.Name = options.Name
+		merged.BinaryHeader = options.BinaryHeader
+	}
+
+	merged.Triangles = make([]Triangle, 0, count)
+	for source, s := range solids {
+		if s == nil {
+			continue
+		}
+		start := len(merged.Triangles)
+		merged.Triangles = append(merged.Triangles, s.Triangles...)
+		if options.TagSources {
+			for i := start; i < len(merged.Triangles); i++ {
+				merged.Triangles[i].Attributes = uint16(source)
+			}
+		}
+	}
+
+	if options.RemoveInternalFaces || options.RemoveDuplicates {
+		merged.removeCoincident(options.RemoveInternalFaces, options.RemoveDuplicates, options.WeldTolerance)
+	}
+	return merged
+}
+
+// removeCoincident removes triangles with equal vertices, keeping the order of the
+// remaining ones. If opposite is true, pairs of triangles with opposite vertex order
+// are removed, if duplicates is true, triangles with the same vertex order as an
+// earlier one.
+func (s *Solid) removeCoincident(opposite, duplicates bool, weldTolerance float32) {
+	m := NewIndexedMesh(s, weldTolerance)
+	keep := make([]bool, len(m.Faces))
+	seen := make(map[[3]uint32]bool)
+	unpaired := make(map[[3]uint32][]int)
+	for f, face := range m.Faces {
+		keep[f] = true
+		if face[0] == face[1] || face[0] == face[2] || face[1] == face[2] {
+			continue
+		}
+		key := rotatedFace(face)
+		if duplicates {
+			if seen[key] {
+				keep[f] = false
+				continue
+			}
+			seen[key] = true
+		}
+		if opposite {
+			reversed := rotatedFace([3]uint32{face[0], face[2], face[1]})
+			if others := unpaired[reversed]; len(others) > 0 {
+				keep[f] = false
+				keep[others[len(others)-1]] = false
+				unpaired[reversed] = others[:len(others)-1]
+				continue
+			}
+			unpaired[key] = append(unpaired[key], f)
+		}
+	}
+
+	n := 0
+	for i := range s.Triangles {
+		if keep[i] {
+			s.Triangles[n] = s.Triangles[i]
+			n++
+		}
+	}
+	s.Triangles = s.Triangles[:n]
+}
+
+// rotatedFace rotates the vertex indices of face, so that the smallest one is
+// first, keeping their cyclic order. Faces with the same vertices and the same
+// orientation are equal after rotation.
+func rotatedFace(face [3]uint32) [3]uint32 {
+	switch {
+	case face[1] < face[0] && face[1] < face[2]:
+		return [3]uint32{face[1], face[2], face[0]}
+	case face[2] < face[0] && face[2] < face[1]:
+		return [3]uint32{face[2], face[0], face[1]}
+	}
+	return face
+}
@@ -0,0 +1,70 @@
+package stl
+
+// Tests for merging solids
+
+import (
+	"testing"
+)
+
+func TestMerge(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	a.Name = "a"
+	a.BinaryHeader = []byte("header a")
+	b := makeTestSolid()
+	b.Name = "b"
+
+	merged := Merge(a, nil, b)
+	if len(merged.Triangles) != 16 {
+		t.Errorf("expected 16 triangles, got %d", len(merged.Triangles))
+	}
+	if merged.Name != "a" || string(merged.BinaryHeader) != "header a" || !merged.IsAscii {
+		t.Errorf("expected name, header and format of first solid, got %q %q %v", merged.Name, merged.BinaryHeader, merged.IsAscii)
+	}
+	if len(a.Triangles) != 12 {
+		t.Error("Merge modified its input")
+	}
+
+	merged = MergeWithOptions(MergeOptions{Naming: MergeNameJoin, TagSources: true}, a, b)
+	if merged.Name != "a+b" {
+		t.Errorf("expected joined name a+b, got %q", merged.Name)
+	}
+	if merged.Triangles[11].Attributes != 0 || merged.Triangles[12].Attributes != 1 {
+		t.Errorf("unexpected source tags %d and %d", merged.Triangles[11].Attributes, merged.Triangles[12].Attributes)
+	}
+
+	merged = MergeWithOptions(MergeOptions{Naming: MergeNameCustom, Name: "custom"}, a, b)
+	if merged.Name != "custom" || merged.BinaryHeader != nil {
+		t.Errorf("expected custom name and empty header, got %q %q", merged.Name, merged.BinaryHeader)
+	}
+}
+
+func TestMergeRemoveCoincident(t *testing.T) {
+	a := makeTestCube(Vec3{0, 0, 0}, 1)
+	b := makeTestCube(Vec3{1, 0, 0}, 1)
+
+	merged := MergeWithOptions(MergeOptions{RemoveInternalFaces: true}, a, b)
+	if len(merged.Triangles) != 20 {
+		t.Fatalf("expected 20 triangles without the touching faces, got %d", len(merged.Triangles))
+	}
+	if errors := merged.Validate(); len(errors) != 0 {
+		t.Errorf("merged cubes are not valid: %v", errors)
+	}
+	if v := merged.Volume(); !almostEqual64(v, 2, 1e-6) {
+		t.Errorf("expected volume 2, got %g", v)
+	}
+
+	merged = MergeWithOptions(MergeOptions{RemoveDuplicates: true}, a, a)
+	if len(merged.Triangles) != 12 {
+		t.Errorf("expected 12 triangles without duplicates, got %d", len(merged.Triangles))
+	}
+
+	// shifted slightly, only removed with tolerance
+	c := makeTestCube(Vec3{1.00001, 0, 0}, 1)
+	if merged := MergeWithOptions(MergeOptions{RemoveInternalFaces: true}, a, c); len(merged.Triangles) != 24 {
+		t.Errorf("expected 24 triangles without tolerance, got %d", len(merged.Triangles))
+	}
+	merged = MergeWithOptions(MergeOptions{RemoveInternalFaces: true, WeldTolerance: 0.001}, a, c)
+	if len(merged.Triangles) != 20 {
+		t.Errorf("expected 20 triangles with tolerance, got %d", len(merged.Triangles))
+	}
+}
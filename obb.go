@@ -0,0 +1,338 @@
+package stl
+
+// This file contains the calculation of oriented bounding boxes
+
+import (
+	"math"
+	"sort"
+)
+
+// OrientedBox is a box that is not necessarily aligned with the coordinate
+// axes, as returned by Solid.OrientedBoundingBox and
+// Solid.ApproximateMinimumBoundingBox.
+type OrientedBox struct {
+	// Center is the centre of the box.
+	Center Vec3
+
+	// Axes are the directions of the edges of the box as unit vectors,
+	// forming a right-handed system, ordered by descending extent.
+	Axes [3]Vec3
+
+	// Extents are the edge lengths of the box along Axes.
+	Extents [3]float64
+}
+
+// Volume returns the volume of the box.
+func (b *OrientedBox) Volume() float64 {
+	return b.Extents[0] * b.Extents[1] * b.Extents[2]
+}
+
+// AlignMatrix calculates a 4x4 matrix that rotates and moves the box, so
+// that its axes are aligned with the x, y, and z axis, and its minimum corner
+// is at the origin. Applied to the solid the box was calculated for using
+// Solid.Transform, the solid fits into the axis aligned box from the origin to
+// Extents, so it is longest along the x axis, and flattest along the z axis.
+// The result is written into *alignMatrix.
+func (b *OrientedBox) AlignMatrix(alignMatrix *Mat4) {
+	c := toVec64(b.Center)
+	// The rows of the rotation are the axes, so Axes[i] is mapped to the
+	// i-th unit vector, and the centre to half the extents.
+	for row := 0; row < 3; row++ {
+		axis := toVec64(b.Axes[row])
+		for col := 0; col < 3; col++ {
+			alignMatrix[row][col] = axis[col]
+		}
+		alignMatrix[row][3] = b.Extents[row]/2 - axis.dot(c)
+	}
+	alignMatrix[3] = Vec4{0, 0, 0, 1}
+}
+
+// OrientedBoundingBox returns a box around s aligned with the principal
+// axes of its surface, i.e. the eigenvectors of the covariance of all points
+// on its triangles. As the covariance is weighted by area, the result does
+// not depend on how finely s is triangulated. It is quick to calculate, but
+// may be noticeably larger than the ApproximateMinimumBoundingBox,
+// especially for solids with symmetries. If s has no triangles, the box is
+// empty, and aligned with the coordinate axes.
+func (s *Solid) OrientedBoundingBox() OrientedBox {
+	points := s.distinctVertices()
+	return boxAlongAxes(points, s.surfacePrincipalAxes())
+}
+
+// ApproximateMinimumBoundingBox returns a box around s with approximately
+// minimal volume, calculated from its convex hull. Candidate directions for
+// one of the axes of the box are the normals of the faces of the hull, and
+// the directions perpendicular to two edges on opposite sides of the hull,
+// which lie in opposite faces of the box. For every candidate, the other two
+// axes are found by rotating calipers. The result is the minimum whenever a
+// face of the minimal box is flush with a face of the hull, or two opposite
+// faces with edges of it. Boxes with only two adjacent faces flush with edges
+// of the hull are not searched, so otherwise the result may be larger than
+// the minimum, but it is never larger than the OrientedBoundingBox, or the
+// axis aligned box. It takes time proportional to the square of the number
+// of hull edges, plus the number of candidate directions times the number of
+// hull vertices.
+func (s *Solid) ApproximateMinimumBoundingBox() OrientedBox {
+	points := s.distinctVertices()
+	best := boxAlongAxes(points, [3]vec64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}})
+	if pca := boxAlongAxes(points, s.surfacePrincipalAxes()); pca.Volume() < best.Volume() {
+		best = pca
+	}
+	triangles := convexHull(points)
+	if len(triangles) == 0 {
+		return best
+	}
+
+	// the vertices of the hull, and the normals of its faces
+	inHull := make(map[int]bool)
+	var hullPoints []vec64
+	faceNormals := make([]vec64, len(triangles))
+	faceOf := make(map[[2]int]int, 3*len(triangles))
+	for f, t := range triangles {
+		for i, v := range t {
+			if !inHull[v] {
+				inHull[v] = true
+				hullPoints = append(hullPoints, points[v])
+			}
+			faceOf[[2]int{v, t[(i+1)%3]}] = f
+		}
+		a := points[t[0]]
+		faceNormals[f] = points[t[1]].sub(a).cross(points[t[2]].sub(a)).unit()
+	}
+
+	// the edges between faces that are not coplanar
+	var edges []hullEdge
+	for f, t := range triangles {
+		for i, a := range t {
+			b := t[(i+1)%3]
+			g, ok := faceOf[[2]int{b, a}]
+			if !ok || a > b {
+				continue
+			}
+			e := hullEdge{direction: points[b].sub(points[a]).unit(), normals: [2]vec64{faceNormals[f], faceNormals[g]}}
+			e.between = e.normals[0].cross(e.normals[1])
+			if e.between.len() > 1e-9 {
+				edges = append(edges, e)
+			}
+		}
+	}
+
+	var candidates []vec64
+	seen := make(map[vec64]bool)
+	addCandidate := func(n vec64) {
+		if n[0] < 0 || n[0] == 0 && (n[1] < 0 || n[1] == 0 && n[2] < 0) {
+			n = n.scale(-1) // opposite directions give the same boxes
+		}
+		if !seen[n] {
+			seen[n] = true
+			candidates = append(candidates, n)
+		}
+	}
+	for _, n := range faceNormals {
+		addCandidate(n)
+	}
+	for i := range edges {
+		for j := i + 1; j < len(edges); j++ {
+			n := edges[i].direction.cross(edges[j].direction)
+			if n.len() <= 1e-9 {
+				continue
+			}
+			n = n.unit()
+			if edges[i].supports(n) && edges[j].supports(n.scale(-1)) ||
+				edges[i].supports(n.scale(-1)) && edges[j].supports(n) {
+				addCandidate(n)
+			}
+		}
+	}
+
+	var scale float64
+	for _, p := range hullPoints {
+		for c := 0; c < 3; c++ {
+			scale = math.Max(scale, math.Abs(p[c]))
+		}
+	}
+	eps := 1e-10 * scale
+	bestVolume := best.Volume()
+	var bestAxes [3]vec64
+	found := false
+	projected := make([][2]float64, len(hullPoints))
+	for _, n := range candidates {
+		u, v := planeBasis(n)
+		minN, maxN := math.Inf(1), math.Inf(-1)
+		for i, p := range hullPoints {
+			projected[i] = [2]float64{p.dot(u), p.dot(v)}
+			minN, maxN = math.Min(minN, p.dot(n)), math.Max(maxN, p.dot(n))
+		}
+		polygon := convexHull2(projected, eps)
+		if len(polygon) < 3 {
+			continue
+		}
+		ring := make([][2]float64, len(polygon))
+		for i, p := range polygon {
+			ring[i] = projected[p]
+		}
+		direction, area := minimumAreaRectangle(ring)
+		if volume := area * (maxN - minN); volume < bestVolume {
+			bestVolume = volume
+			bestAxes = [3]vec64{
+				u.scale(direction[0]).add(v.scale(direction[1])),
+				u.scale(-direction[1]).add(v.scale(direction[0])),
+				n,
+			}
+			found = true
+		}
+	}
+	if found {
+		best = boxAlongAxes(points, bestAxes)
+	}
+	return best
+}
+
+// hullEdge is an edge of a convex hull between two faces that are not
+// coplanar.
+type hullEdge struct {
+	direction vec64    // unit length
+	normals   [2]vec64 // of the adjacent faces
+	between   vec64    // cross product of the normals
+}
+
+// supports returns true if a plane with normal n, which has to be
+// perpendicular to e, touches the hull along e, i.e. if n lies between the
+// normals of its faces.
+func (e *hullEdge) supports(n vec64) bool {
+	tol := -1e-9 * e.between.len()
+	return e.normals[0].cross(n).dot(e.between) >= tol && n.cross(e.normals[1]).dot(e.between) >= tol
+}
+
+// minimumAreaRectangle returns the direction of an edge of the rectangle
+// with minimal area around the convex polygon ring, which has to be
+// counter-clockwise, and its area, using rotating calipers. One edge of the
+// rectangle is always flush with an edge of the polygon.
+func minimumAreaRectangle(ring [][2]float64) (direction [2]float64, area float64) {
+	n := len(ring)
+	dot := func(a, b [2]float64) float64 { return a[0]*b[0] + a[1]*b[1] }
+	next := func(i int) int { return (i + 1) % n }
+	area = math.Inf(1)
+	var right, top, left int
+	initialized := false
+	for i := 0; i < n; i++ {
+		e := sub2(ring[next(i)], ring[i])
+		l := math.Hypot(e[0], e[1])
+		if l == 0 {
+			continue
+		}
+		e = [2]float64{e[0] / l, e[1] / l}
+		normal := [2]float64{-e[1], e[0]} // pointing inside
+		if !initialized {
+			initialized = true
+			right, top, left = i, i, i
+			for j := range ring {
+				if dot(ring[j], e) > dot(ring[right], e) {
+					right = j
+				}
+				if dot(ring[j], normal) > dot(ring[top], normal) {
+					top = j
+				}
+				if dot(ring[j], e) < dot(ring[left], e) {
+					left = j
+				}
+			}
+		} else {
+			// the calipers only move forward around the polygon
+			for k := 0; k < n && dot(ring[next(right)], e) > dot(ring[right], e); k++ {
+				right = next(right)
+			}
+			for k := 0; k < n && dot(ring[next(top)], normal) > dot(ring[top], normal); k++ {
+				top = next(top)
+			}
+			for k := 0; k < n && dot(ring[next(left)], e) < dot(ring[left], e); k++ {
+				left = next(left)
+			}
+		}
+		width := dot(sub2(ring[right], ring[left]), e)
+		height := dot(sub2(ring[top], ring[i]), normal)
+		if a := width * height; a < area {
+			area = a
+			direction = e
+		}
+	}
+	return direction, area
+}
+
+// surfacePrincipalAxes returns the eigenvectors of the covariance matrix of
+// the points on the triangles of s, weighted by area.
+func (s *Solid) surfacePrincipalAxes() [3]vec64 {
+	if len(s.Triangles) == 0 {
+		return [3]vec64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+	// relative to a vertex, to avoid cancellation far from the origin
+	ref := toVec64(s.Triangles[0].Vertices[0])
+	var area float64
+	var mean vec64
+	var second Mat3
+	for i := range s.Triangles {
+		var t triangle64
+		t[0], t[1], t[2] = s.Triangles[i].relativeVertices(ref)
+		a := t.normal().len() / 2
+		centroid := t[0].add(t[1]).add(t[2]).scale(1.0 / 3)
+		area += a
+		mean = mean.add(centroid.scale(a))
+		// the second moment of a triangle about the origin
+		for r := 0; r < 3; r++ {
+			for c := 0; c < 3; c++ {
+				second[r][c] += a / 12 * (9*centroid[r]*centroid[c] +
+					t[0][r]*t[0][c] + t[1][r]*t[1][c] + t[2][r]*t[2][c])
+			}
+		}
+	}
+	if area == 0 {
+		return [3]vec64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+	}
+	mean = mean.scale(1 / area)
+	var covariance Mat3
+	for r := 0; r < 3; r++ {
+		for c := 0; c < 3; c++ {
+			covariance[r][c] = second[r][c]/area - mean[r]*mean[c]
+		}
+	}
+	_, axes := covariance.symmetricEigen()
+	return axes
+}
+
+// boxAlongAxes returns the smallest box around points with edges along the
+// three orthogonal unit vectors axes.
+func boxAlongAxes(points []vec64, axes [3]vec64) OrientedBox {
+	type axisExtent struct {
+		axis     vec64
+		min, max float64
+	}
+	extents := make([]axisExtent, 3)
+	for i, axis := range axes {
+		extents[i] = axisExtent{axis, math.Inf(1), math.Inf(-1)}
+		for _, p := range points {
+			d := axis.dot(p)
+			extents[i].min = math.Min(extents[i].min, d)
+			extents[i].max = math.Max(extents[i].max, d)
+		}
+		if len(points) == 0 {
+			extents[i].min, extents[i].max = 0, 0
+		}
+	}
+	sort.SliceStable(extents, func(i, j int) bool {
+		return extents[i].max-extents[i].min > extents[j].max-extents[j].min
+	})
+	if extents[0].axis.cross(extents[1].axis).dot(extents[2].axis) < 0 {
+		extents[2].axis = extents[2].axis.scale(-1)
+		extents[2].min, extents[2].max = -extents[2].max, -extents[2].min
+	}
+
+	var b OrientedBox
+	var center vec64
+	for i, e := range extents {
+		b.Axes[i] = e.axis.vec3()
+		b.Extents[i] = e.max - e.min
+		center = center.add(e.axis.scale((e.min + e.max) / 2))
+	}
+	b.Center = center.vec3()
+	return b
+}
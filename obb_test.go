@@ -0,0 +1,112 @@
+package stl
+
+// Tests for oriented bounding boxes
+
+import (
+	"math"
+	"testing"
+)
+
+// makeTestBrick returns a closed box with the given edge lengths, rotated
+// into a general orientation, and the directions of its edges.
+func makeTestBrick(size Vec3) (s *Solid, axes [3]Vec3) {
+	s = makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Stretch(size)
+	s.Translate(Vec3{5, -3, 2})
+	var rotation, second, combined Mat4
+	RotationMatrix(Vec3{0, 0, 0}, Vec3{1, 2, 3}, 0.7, &rotation)
+	RotationMatrix(Vec3{0, 0, 0}, Vec3{-2, 0, 1}, 1.1, &second)
+	second.MultMat4(&rotation, &combined)
+	s.Transform(&combined)
+	for i := range axes {
+		var unit Vec3
+		unit[i] = 1
+		axes[i] = combined.MultVec3(unit).Diff(combined.MultVec3(Vec3{}))
+	}
+	return s, axes
+}
+
+func checkOrientedBox(t *testing.T, name string, s *Solid, b OrientedBox, extents [3]float64, tol float64) {
+	t.Helper()
+	for i := 0; i < 3; i++ {
+		if !almostEqual64(b.Extents[i], extents[i], tol) {
+			t.Errorf("%s: expected extents %v, got %v", name, extents, b.Extents)
+			break
+		}
+	}
+	if d := toVec64(b.Axes[0]).cross(toVec64(b.Axes[1])).dot(toVec64(b.Axes[2])); !almostEqual64(d, 1, 1e-6) {
+		t.Errorf("%s: axes %v are not a right-handed orthonormal system", name, b.Axes)
+	}
+	var m Mat4
+	b.AlignMatrix(&m)
+	aligned := &Solid{Triangles: append([]Triangle(nil), s.Triangles...)}
+	aligned.Transform(&m)
+	measure := aligned.Measure()
+	for i := 0; i < 3; i++ {
+		if !almostEqual64(float64(measure.Min[i]), 0, 1e-4) || !almostEqual64(float64(measure.Max[i]), b.Extents[i], 1e-4) {
+			t.Errorf("%s: expected aligned solid from 0 to %v, got %v to %v", name, b.Extents, measure.Min, measure.Max)
+			break
+		}
+	}
+}
+
+func TestOrientedBoundingBox(t *testing.T) {
+	s, axes := makeTestBrick(Vec3{4, 2, 1})
+	b := s.OrientedBoundingBox()
+	checkOrientedBox(t, "PCA", s, b, [3]float64{4, 2, 1}, 1e-4)
+	for i := range axes {
+		if d := math.Abs(toVec64(b.Axes[i]).dot(toVec64(axes[i]).unit())); !almostEqual64(d, 1, 1e-5) {
+			t.Errorf("expected axis %d along %v, got %v", i, axes[i], b.Axes[i])
+		}
+	}
+	checkOrientedBox(t, "minimum", s, s.ApproximateMinimumBoundingBox(), [3]float64{4, 2, 1}, 1e-4)
+}
+
+func TestApproximateMinimumBoundingBox(t *testing.T) {
+	// the surface of a cube has no distinct principal axes
+	s, _ := makeTestBrick(Vec3{2, 2, 2})
+	pca := s.OrientedBoundingBox()
+	b := s.ApproximateMinimumBoundingBox()
+	checkOrientedBox(t, "cube", s, b, [3]float64{2, 2, 2}, 1e-4)
+	if b.Volume() > pca.Volume()+1e-6 {
+		t.Errorf("minimum box volume %g larger than PCA box volume %g", b.Volume(), pca.Volume())
+	}
+
+	torus := makeTestTorus(3, 1, 32, 16)
+	torus.Rotate(Vec3{0, 0, 0}, Vec3{1, 1, 0}, 0.5)
+	b = torus.ApproximateMinimumBoundingBox()
+	checkOrientedBox(t, "torus", torus, b, [3]float64{8, 8, 2}, 0.05)
+
+	// the minimal box of a regular tetrahedron is the cube with one of its
+	// edges on every face, no face of the box is flush with a face of it
+	v := [4]Vec3{{0.5, 0.5, 0.5}, {0.5, -0.5, -0.5}, {-0.5, 0.5, -0.5}, {-0.5, -0.5, 0.5}}
+	tetrahedron := &Solid{}
+	for _, f := range [4][3]int{{0, 1, 2}, {0, 3, 1}, {0, 2, 3}, {1, 3, 2}} {
+		tetrahedron.Triangles = append(tetrahedron.Triangles, Triangle{Vertices: [3]Vec3{v[f[0]], v[f[1]], v[f[2]]}})
+	}
+	tetrahedron.Rotate(Vec3{0, 0, 0}, Vec3{1, 2, 3}, 0.7)
+	tetrahedron.Translate(Vec3{5, -3, 2})
+	b = tetrahedron.ApproximateMinimumBoundingBox()
+	checkOrientedBox(t, "tetrahedron", tetrahedron, b, [3]float64{1, 1, 1}, 1e-4)
+
+	b = (&Solid{}).ApproximateMinimumBoundingBox()
+	if b.Volume() != 0 || b.Axes[0] != (Vec3{1, 0, 0}) {
+		t.Errorf("expected empty box along the axes, got %+v", b)
+	}
+}
+
+func TestMinimumAreaRectangle(t *testing.T) {
+	// a square rotated by 30 degrees
+	var ring [][2]float64
+	for i := 0; i < 4; i++ {
+		a := Pi/6 + float64(i)*Pi/2
+		ring = append(ring, [2]float64{math.Cos(a), math.Sin(a)})
+	}
+	direction, area := minimumAreaRectangle(ring)
+	if !almostEqual64(area, 2, 1e-9) {
+		t.Errorf("expected area 2, got %g", area)
+	}
+	if d := math.Abs(direction[0]*math.Cos(Pi/6+3*Pi/4) + direction[1]*math.Sin(Pi/6+3*Pi/4)); !almostEqual64(d, 1, 1e-9) && !almostEqual64(d, 0, 1e-9) {
+		t.Errorf("expected direction along an edge, got %v", direction)
+	}
+}
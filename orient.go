@@ -0,0 +1,193 @@
+package stl
+
+// This file contains functions to orient the triangles of a solid
+// consistently
+
+import (
+	"math"
+)
+
+// OrientConsistently flips triangles, i.e. reverses their vertex order and
+// normal vector, so that every edge shared by two triangles is used in
+// opposite directions, and every shell faces outwards. A shell is a group of
+// triangles connected by such edges. Shells nested inside an odd number of
+// other shells are cavities, and face inwards. The direction of a shell is
+// determined by the sign of its volume, see Volume, and the nesting by the
+// generalized winding number of the other shells. Shells without volume are
+// oriented like the majority of their triangles. Returns the number of
+// triangles flipped.
+func (s *Solid) OrientConsistently() int {
+	m := NewIndexedMesh(s, 0)
+	flipped := 0
+	for i, flip := range m.orientation() {
+		if flip {
+			t := &s.Triangles[i]
+			t.Vertices[1], t.Vertices[2] = t.Vertices[2], t.Vertices[1]
+			t.Normal = t.Normal.MultScalar(-1)
+			flipped++
+		}
+	}
+	return flipped
+}
+
+// orientation returns which faces of m have to be flipped, as described
+// for Solid.OrientConsistently.
+func (m *IndexedMesh) orientation() []bool {
+	flip, labels, count := propagateWinding(m.Faces)
+
+	shellFaces := make([][]int, count)
+	for f, label := range labels {
+		shellFaces[label] = append(shellFaces[label], f)
+	}
+
+	// signed volume and bounding box of every shell after flipping
+	sixVolume := make([]float64, count)
+	boxMin := make([]vec64, count)
+	boxMax := make([]vec64, count)
+	for label, faces := range shellFaces {
+		ref := toVec64(m.Vertices[m.Faces[faces[0]][0]])
+		boxMin[label], boxMax[label] = ref, ref
+		for _, f := range faces {
+			a, b, c := m.faceVertices(f, ref)
+			if flip[f] {
+				b, c = c, b
+			}
+			sixVolume[label] += a.dot(b.cross(c))
+			for _, v := range [3]vec64{a, b, c} {
+				for d := 0; d < 3; d++ {
+					boxMin[label][d] = math.Min(boxMin[label][d], v[d]+ref[d])
+					boxMax[label][d] = math.Max(boxMax[label][d], v[d]+ref[d])
+				}
+			}
+		}
+	}
+
+	for label, faces := range shellFaces {
+		var outwards bool
+		if sixVolume[label] != 0 {
+			// count the shells containing this one, by testing one of its vertices
+			depth := 0
+			p := toVec64(m.Vertices[m.Faces[faces[0]][0]])
+			for other, otherFaces := range shellFaces {
+				if other == label || !boxContains(boxMin[other], boxMax[other], boxMin[label], boxMax[label]) {
+					continue
+				}
+				var solidAngle float64
+				for _, f := range otherFaces {
+					a, b, c := m.faceVertices(f, p)
+					if flip[f] {
+						b, c = c, b
+					}
+					solidAngle += triangleSolidAngle(a, b, c)
+				}
+				if math.Abs(solidAngle) > TwoPi {
+					depth++
+				}
+			}
+			outwards = (sixVolume[label] > 0) == (depth%2 == 0)
+		} else {
+			flipCount := 0
+			for _, f := range faces {
+				if flip[f] {
+					flipCount++
+				}
+			}
+			outwards = 2*flipCount <= len(faces)
+		}
+		if !outwards {
+			for _, f := range faces {
+				flip[f] = !flip[f]
+			}
+		}
+	}
+	return flip
+}
+
+// faceVertices returns the vertices of face f in double precision, relative to ref.
+func (m *IndexedMesh) faceVertices(f int, ref vec64) (a, b, c vec64) {
+	face := m.Faces[f]
+	return toVec64(m.Vertices[face[0]]).sub(ref),
+		toVec64(m.Vertices[face[1]]).sub(ref),
+		toVec64(m.Vertices[face[2]]).sub(ref)
+}
+
+// boxContains returns true if the axis aligned box innerMin..innerMax lies within outerMin..outerMax.
+func boxContains(outerMin, outerMax, innerMin, innerMax vec64) bool {
+	for d := 0; d < 3; d++ {
+		if innerMin[d] < outerMin[d] || innerMax[d] > outerMax[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// triangleSolidAngle returns the signed solid angle of the triangle a, b, c
+// seen from the origin, using the formula of Van Oosterom and Strackee.
+// The sum over a closed surface is ±4π for points inside, and 0 outside.
+func triangleSolidAngle(a, b, c vec64) float64 {
+	la, lb, lc := a.len(), b.len(), c.len()
+	numerator := a.dot(b.cross(c))
+	denominator := la*lb*lc + a.dot(b)*lc + a.dot(c)*lb + b.dot(c)*la
+	return 2 * math.Atan2(numerator, denominator)
+}
+
+// propagateWinding groups faces connected by edges shared by exactly two
+// faces. Within every group, starting at its first face, it determines
+// which faces have to be flipped so that these edges are used in opposite
+// directions. If that is impossible, e.g. for a Möbius strip, the first
+// decision wins. Returns the flip decision and the group label of every
+// face, and the number of groups.
+func propagateWinding(faces [][3]uint32) (flip []bool, labels []int, count int) {
+	edgeFaces := make(map[[2]uint32][]int, 3*len(faces)/2)
+	for f, face := range faces {
+		for v := 0; v < 3; v++ {
+			key := undirectedEdge(face[v], face[(v+1)%3])
+			edgeFaces[key] = append(edgeFaces[key], f)
+		}
+	}
+
+	flip = make([]bool, len(faces))
+	labels = make([]int, len(faces))
+	for f := range labels {
+		labels[f] = -1
+	}
+	var queue []int
+	for start := range faces {
+		if labels[start] >= 0 {
+			continue
+		}
+		labels[start] = count
+		queue = append(queue[:0], start)
+		for len(queue) > 0 {
+			f := queue[0]
+			queue = queue[1:]
+			for v := 0; v < 3; v++ {
+				a, b := faces[f][v], faces[f][(v+1)%3]
+				neighbors := edgeFaces[undirectedEdge(a, b)]
+				if len(neighbors) != 2 {
+					continue
+				}
+				g := neighbors[0]
+				if g == f {
+					g = neighbors[1]
+				}
+				if labels[g] >= 0 {
+					continue
+				}
+				labels[g] = count
+				// g keeps its orientation relative to f if it uses the edge as b -> a
+				flip[g] = flip[f] != faceHasEdge(faces[g], a, b)
+				queue = append(queue, g)
+			}
+		}
+		count++
+	}
+	return flip, labels, count
+}
+
+// faceHasEdge returns true if face contains the directed edge a -> b.
+func faceHasEdge(face [3]uint32, a, b uint32) bool {
+	return (face[0] == a && face[1] == b) ||
+		(face[1] == a && face[2] == b) ||
+		(face[2] == a && face[0] == b)
+}
@@ -0,0 +1,73 @@
+package stl
+
+// Tests for the consistent orientation of solids
+
+import (
+	"testing"
+)
+
+func TestOrientConsistently(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	for i := range s.Triangles {
+		vs := &s.Triangles[i].Vertices
+		vs[1], vs[2] = vs[2], vs[1]
+		s.Triangles[i].recalculateNormal()
+	}
+	// one triangle correct within the inside-out cube
+	s.Triangles[4].Vertices[1], s.Triangles[4].Vertices[2] = s.Triangles[4].Vertices[2], s.Triangles[4].Vertices[1]
+	s.Triangles[4].recalculateNormal()
+
+	if flipped := s.OrientConsistently(); flipped != 11 {
+		t.Errorf("expected 11 flipped triangles, got %d", flipped)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("oriented cube is not valid: %v", errors)
+	}
+	if v := s.Volume(); !almostEqual64(v, 1, 1e-6) {
+		t.Errorf("expected volume 1, got %g", v)
+	}
+}
+
+func TestOrientConsistentlyCavity(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 4)
+	// the cavity is wrongly facing outwards
+	s.Triangles = append(s.Triangles, makeTestCube(Vec3{1, 1, 1}, 1).Triangles...)
+	// a separate cube inside-out
+	other := makeTestCube(Vec3{10, 0, 0}, 2)
+	for i := range other.Triangles {
+		vs := &other.Triangles[i].Vertices
+		vs[1], vs[2] = vs[2], vs[1]
+		other.Triangles[i].recalculateNormal()
+	}
+	s.Triangles = append(s.Triangles, other.Triangles...)
+
+	if flipped := s.OrientConsistently(); flipped != 24 {
+		t.Errorf("expected 24 flipped triangles, got %d", flipped)
+	}
+	if v := s.Volume(); !almostEqual64(v, 64-1+8, 1e-6) {
+		t.Errorf("expected volume 71, got %g", v)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("oriented solid is not valid: %v", errors)
+	}
+}
+
+func TestOrientConsistentlyCavityPartlyFlipped(t *testing.T) {
+	for _, n := range []int{4, 6, 8} {
+		s := makeTestCube(Vec3{0, 0, 0}, 3)
+		for i := 0; i < n; i++ {
+			vs := &s.Triangles[i].Vertices
+			vs[1], vs[2] = vs[2], vs[1]
+			s.Triangles[i].recalculateNormal()
+		}
+		s.Triangles = append(s.Triangles, makeTestCube(Vec3{1, 1, 1}, 1).Triangles...)
+
+		s.OrientConsistently()
+		if v := s.Volume(); !almostEqual64(v, 27-1, 1e-6) {
+			t.Errorf("%d outer triangles flipped: expected volume 26, got %g", n, v)
+		}
+		if errors := s.Validate(); len(errors) != 0 {
+			t.Errorf("%d outer triangles flipped: oriented solid is not valid: %v", n, errors)
+		}
+	}
+}
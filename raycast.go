@@ -0,0 +1,159 @@
+package stl
+
+// This file contains ray casting, and tests whether points are inside a solid
+
+import (
+	"math"
+	"sort"
+)
+
+// RayCast returns where the ray from origin into direction hits a triangle
+// of s first, see BVH.FirstHit. It tests every triangle, so for many rays
+// it is faster to build a BVH once, and use its FirstHit method.
+func (s *Solid) RayCast(origin, direction Vec3) (hit RayHit, ok bool) {
+	o, d := toVec64(origin), toVec64(direction)
+	r := newRay64(o, d)
+	hit.Distance = math.Inf(1)
+	for i := range s.Triangles {
+		t := s.Triangles[i].triangle64()
+		if dist, u, v, found := r.intersect(&t); found && dist < hit.Distance {
+			hit = RayHit{Triangle: i, Distance: dist, U: u, V: v}
+			ok = true
+		}
+	}
+	if ok {
+		hit.Point = o.add(d.scale(hit.Distance)).vec3()
+	}
+	return hit, ok
+}
+
+// RayCastAll returns all places where the ray from origin into direction
+// hits a triangle of s, ordered by distance, see BVH.AllHits and RayCast.
+func (s *Solid) RayCastAll(origin, direction Vec3) []RayHit {
+	o, d := toVec64(origin), toVec64(direction)
+	r := newRay64(o, d)
+	var hits []RayHit
+	for i := range s.Triangles {
+		t := s.Triangles[i].triangle64()
+		if dist, u, v, found := r.intersect(&t); found {
+			hits = append(hits, RayHit{Triangle: i, Distance: dist, Point: o.add(d.scale(dist)).vec3(), U: u, V: v})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Distance != hits[j].Distance {
+			return hits[i].Distance < hits[j].Distance
+		}
+		return hits[i].Triangle < hits[j].Triangle
+	})
+	return hits
+}
+
+// WindingNumber returns the generalized winding number of s at p, the sum
+// of the signed solid angles of all triangles seen from p, divided by 4π.
+// For a closed solid facing outwards it is 1 inside and 0 outside, for
+// solids with holes or overlapping shells it changes gradually in between.
+func (s *Solid) WindingNumber(p Vec3) float64 {
+	q := toVec64(p)
+	var solidAngle float64
+	for i := range s.Triangles {
+		a, b, c := s.Triangles[i].relativeVertices(q)
+		solidAngle += triangleSolidAngle(a, b, c)
+	}
+	return solidAngle / (2 * TwoPi)
+}
+
+// Contains returns true if p is inside s, i.e. if the absolute value of the
+// WindingNumber is at least 0.5. As no rays are involved, the result does
+// not depend on rays grazing edges or vertices, and also makes sense for
+// solids that are not quite closed, or inside-out. Points on the surface may
+// be considered inside or outside. It takes time proportional to the number
+// of triangles, for many points BVH.Contains is faster.
+func (s *Solid) Contains(p Vec3) bool {
+	return math.Abs(s.WindingNumber(p)) >= 0.5
+}
+
+// containsDirections are the ray directions tried by BVH.Contains, chosen to
+// be unlikely to run along the edges and faces of typical models.
+var containsDirections = []vec64{
+	{0.5773502691896258, 0.5773502691896258, 0.5773502691896258},
+	{-0.3281650446839734, 0.7316007389521473, 0.5975935212870112},
+	{0.2311373457112398, -0.4167018237126311, 0.8792475319014211},
+	{-0.6963231876251409, -0.5132470138437722, -0.5016473216893417},
+	{0.8418038826187437, -0.2934001876311257, -0.4530106339051736},
+	{-0.1165870034311012, 0.3052384913867421, -0.9451106235398514},
+	{0.9115489123357623, 0.3681054318003124, 0.1831540811427413},
+}
+
+// containsRays is the number of rays without ambiguous hits BVH.Contains
+// lets vote.
+const containsRays = 3
+
+// Contains returns true if p is inside the closed solid the BVH was built
+// from, by counting how often rays from p cross its surface. Rays hitting
+// triangles close to an edge or vertex are ambiguous and skipped. The
+// majority of several unambiguous rays decides. If no ray is unambiguous,
+// the winding number decides, like in Solid.Contains. Points on the surface
+// may be considered inside or outside.
+func (b *BVH) Contains(p Vec3) bool {
+	o := toVec64(p)
+	inside, votes := 0, 0
+	for _, d := range containsDirections {
+		crossings, ok := b.crossings(o, d)
+		if !ok {
+			continue
+		}
+		votes++
+		if crossings%2 == 1 {
+			inside++
+		}
+		if votes == containsRays {
+			break
+		}
+	}
+	if votes > 0 {
+		return 2*inside > votes
+	}
+	var solidAngle float64
+	for i := range b.triangles {
+		t := &b.triangles[i]
+		solidAngle += triangleSolidAngle(t[0].sub(o), t[1].sub(o), t[2].sub(o))
+	}
+	return math.Abs(solidAngle) >= TwoPi
+}
+
+// crossings returns the number of triangles the ray from o into direction d
+// hits. ok is false if the ray passes close to an edge, where the count is
+// not reliable.
+func (b *BVH) crossings(o, d vec64) (count int, ok bool) {
+	const margin = 1e-9
+	r := newRay64(o, d)
+	ok = true
+	b.traverse(func(box *box64) bool {
+		_, hit := r.boxEntry(box)
+		return ok && hit
+	}, func(i int) bool {
+		t := &b.triangles[i]
+		if _, u, v, found := r.intersect(t); found {
+			if u < margin || v < margin || u+v > 1-margin {
+				ok = false
+				return false
+			}
+			count++
+		} else if n := t.normal(); math.Abs(n.dot(d)) <= margin*n.len()*d.len() &&
+			math.Abs(n.dot(o.sub(t[0]))) <= margin*n.len()*(1+o.len()) {
+			// running within the plane of the triangle, maybe along its surface
+			box := t.box()
+			if _, hit := r.boxEntry(&box); hit {
+				ok = false
+				return false
+			}
+		}
+		return true
+	})
+	return count, ok
+}
+
+// triangle64 returns the vertices of t in double precision.
+func (t *Triangle) triangle64() triangle64 {
+	return triangle64{toVec64(t.Vertices[0]), toVec64(t.Vertices[1]), toVec64(t.Vertices[2])}
+}
@@ -0,0 +1,76 @@
+package stl
+
+// Tests for ray casting and point-in-solid tests
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestRayCast(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	hit, ok := s.RayCast(Vec3{0.25, 0.5, 3}, Vec3{0, 0, -1})
+	if !ok || hit.Distance != 2 || hit.Point != (Vec3{0.25, 0.5, 1}) || (hit.Triangle != 10 && hit.Triangle != 11) {
+		t.Errorf("unexpected hit %+v", hit)
+	}
+	hits := s.RayCastAll(Vec3{0.25, 0.5, 3}, Vec3{0, 0, -1})
+	if len(hits) != 2 || hits[0] != hit || hits[1].Distance != 3 {
+		t.Errorf("unexpected hits %+v", hits)
+	}
+	if bvhHits := NewBVH(s).AllHits(Vec3{0.25, 0.5, 3}, Vec3{0, 0, -1}); !reflect.DeepEqual(hits, bvhHits) {
+		t.Errorf("BVH hits %+v differ from %+v", bvhHits, hits)
+	}
+	if _, ok := s.RayCast(Vec3{0.25, 0.5, 3}, Vec3{0, 0, 1}); ok {
+		t.Error("unexpected hit of ray pointing away")
+	}
+}
+
+func TestContains(t *testing.T) {
+	cube := makeTestCube(Vec3{0, 0, 0}, 1)
+	torus := makeTestTorus(2, 0.5, 32, 16)
+	insideOut := makeTestCube(Vec3{0, 0, 0}, 1)
+	for i := range insideOut.Triangles {
+		v := &insideOut.Triangles[i].Vertices
+		v[1], v[2] = v[2], v[1]
+	}
+	for _, test := range []struct {
+		name   string
+		s      *Solid
+		p      Vec3
+		inside bool
+	}{
+		{"cube centre", cube, Vec3{0.5, 0.5, 0.5}, true},
+		{"cube outside", cube, Vec3{1.5, 0.5, 0.5}, false},
+		// rays along the diagonals of the faces
+		{"cube diagonal", cube, Vec3{0.25, 0.25, 0.25}, true},
+		{"cube diagonal outside", cube, Vec3{-1, -1, -1}, false},
+		{"torus tube", torus, Vec3{2, 0, 0}, true},
+		{"torus hole", torus, Vec3{0, 0, 0}, false},
+		{"inside-out", insideOut, Vec3{0.5, 0.5, 0.5}, true},
+	} {
+		if inside := test.s.Contains(test.p); inside != test.inside {
+			t.Errorf("%s: Contains returned %v", test.name, inside)
+		}
+		if inside := NewBVH(test.s).Contains(test.p); inside != test.inside {
+			t.Errorf("%s: BVH.Contains returned %v", test.name, inside)
+		}
+	}
+
+	if w := cube.WindingNumber(Vec3{0.5, 0.5, 0.5}); !almostEqual64(w, 1, 1e-9) {
+		t.Errorf("expected winding number 1, got %g", w)
+	}
+	if w := cube.WindingNumber(Vec3{3, 3, 3}); math.Abs(w) > 1e-9 {
+		t.Errorf("expected winding number 0, got %g", w)
+	}
+}
+
+func TestBVHContainsAmbiguous(t *testing.T) {
+	b := NewBVH(makeTestCube(Vec3{0, 0, 0}, 1))
+	if _, ok := b.crossings(vec64{0.5, 0.5, 0.5}, vec64{0.5, 0.5, 0.5}); ok {
+		t.Error("expected ray through corner to be ambiguous")
+	}
+	if count, ok := b.crossings(vec64{0.25, 0.75, 0.5}, vec64{0, 0, 1}); !ok || count != 1 {
+		t.Errorf("expected ray through face interior to cross once, got %d %v", count, ok)
+	}
+}
@@ -13,10 +13,15 @@ import (
 	"strconv"
 )
 
-func readAllASCII(r io.Reader, sw Writer) (err error) {
+func readAllASCII(r io.Reader, sw Writer, options *ReadOptions) (err error) {
 	p := newParser(r)
+	p.options = options
 	if !p.Parse(sw) {
-		err = errors.New(p.ErrorText)
+		if p.FilterError != nil {
+			err = p.FilterError
+		} else {
+			err = errors.New(p.ErrorText)
+		}
 	}
 	return
 }
@@ -29,8 +34,11 @@ type parser struct {
 	eof              bool
 	lineScanner      *bufio.Scanner
 	wordScanner      *bufio.Scanner
+	options          *ReadOptions
+	triangleCount    int
 	HeaderError      bool
 	TrianglesSkipped bool
+	FilterError      error
 	ErrorText        string
 }
 
@@ -38,6 +46,7 @@ func newParser(reader io.Reader) *parser {
 	var p parser
 	p.errors = list.New()
 	p.eof = false
+	p.options = new(ReadOptions)
 	p.lineScanner = bufio.NewScanner(reader)
 	p.nextLine()
 	return &p
@@ -103,7 +112,15 @@ func (p *parser) Parse(sw Writer) bool {
 
 			var t Triangle
 			if p.parseFacet(&t) {
-				sw.AppendTriangle(t)
+				keep, filterErr := p.options.filterTriangle(&t, p.triangleCount)
+				p.triangleCount++
+				if filterErr != nil {
+					p.FilterError = filterErr
+					break TriangleLoop
+				}
+				if keep {
+					sw.AppendTriangle(t)
+				}
 			} else {
 				p.TrianglesSkipped = true
 				p.skipToToken(idFacet | idEndsolid)
@@ -111,7 +128,7 @@ func (p *parser) Parse(sw Writer) bool {
 		}
 	}
 
-	success := !p.HeaderError && !p.TrianglesSkipped && p.consumeToken(idEndsolid)
+	success := p.FilterError == nil && !p.HeaderError && !p.TrianglesSkipped && p.consumeToken(idEndsolid)
 	p.generateErrorText()
 	return success
 }
@@ -12,7 +12,7 @@ import (
 const binaryHeaderSize = 84
 const binaryTriangleSize = 50
 
-func readAllBinary(r io.Reader, sw Writer) (err error) {
+func readAllBinary(r io.Reader, sw Writer, options *ReadOptions) (err error) {
 	var header [binaryHeaderSize]byte
 	n, readErr := r.Read(header[:])
 	if readErr == io.EOF && n != binaryHeaderSize {
@@ -35,7 +35,14 @@ func readAllBinary(r io.Reader, sw Writer) (err error) {
 			err = fmt.Errorf("while reading triangle no. %d at byte %d: %s", i, binaryHeaderSize+i*binaryTriangleSize, readErr.Error())
 			return
 		}
-		sw.AppendTriangle(t)
+		keep, filterErr := options.filterTriangle(&t, int(i))
+		if filterErr != nil {
+			err = filterErr
+			return
+		}
+		if keep {
+			sw.AppendTriangle(t)
+		}
 	}
 
 	return
@@ -6,8 +6,10 @@ package stl
 import (
 	"bufio"
 	"errors"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 )
 
 // ErrIncompleteBinaryHeader is used when reading binary STL files with incomplete header.
@@ -16,12 +18,117 @@ var ErrIncompleteBinaryHeader = errors.New("incomplete STL binary header, 84 byt
 // ErrUnexpectedEOF is used by ReadFile and ReadAll to signify an incomplete file.
 var ErrUnexpectedEOF = errors.New("unexpected end of file")
 
+// NonFiniteError is used when a triangle contains NaN or ±Inf values in its
+// normal vector or its vertices, and the options in use do not allow this.
+type NonFiniteError struct {
+	// TriangleIndex is the index of the offending triangle, counting from 0
+	// in the order of the file or of Solid.Triangles.
+	TriangleIndex int
+}
+
+func (e *NonFiniteError) Error() string {
+	return fmt.Sprintf("triangle no. %d contains non-finite values (NaN or Inf)", e.TriangleIndex)
+}
+
+// TooManyTrianglesError is used when a Solid has more triangles than the
+// uint32 triangle count of the STL binary format can hold.
+type TooManyTrianglesError struct {
+	// Count is len(Solid.Triangles)
+	Count int
+}
+
+func (e *TooManyTrianglesError) Error() string {
+	return fmt.Sprintf("%d triangles do not fit into STL binary format, at most %d allowed", e.Count, maxBinaryTriangleCount)
+}
+
+// NonFiniteMode determines how triangles containing NaN or ±Inf values are
+// treated while reading.
+type NonFiniteMode int
+
+const (
+	// NonFiniteKeep passes triangles with non-finite values on unchanged. This is
+	// the default, and the behaviour of ReadFile, ReadAll, CopyFile, and CopyAll.
+	NonFiniteKeep NonFiniteMode = iota
+
+	// NonFiniteReject stops reading at the first triangle with non-finite values,
+	// returning a *NonFiniteError.
+	NonFiniteReject
+
+	// NonFiniteDrop skips triangles with non-finite values.
+	NonFiniteDrop
+
+	// NonFiniteZero replaces every non-finite value by 0.
+	NonFiniteZero
+)
+
+// ReadOptions control how STL data is read by ReadFileWithOptions,
+// ReadAllWithOptions, CopyFileWithOptions, and CopyAllWithOptions.
+// The zero value corresponds to the behaviour of ReadFile and friends.
+type ReadOptions struct {
+	// NonFinite determines how triangles with NaN or ±Inf values are treated.
+	NonFinite NonFiniteMode
+}
+
+// filterTriangle applies the options to t, which is the triangle with
+// index i in the input. Returns false, if t has to be skipped.
+func (o *ReadOptions) filterTriangle(t *Triangle, i int) (keep bool, err error) {
+	if o.NonFinite == NonFiniteKeep || t.isFinite() {
+		return true, nil
+	}
+	switch o.NonFinite {
+	case NonFiniteReject:
+		return false, &NonFiniteError{TriangleIndex: i}
+	case NonFiniteDrop:
+		return false, nil
+	}
+	t.zeroNonFinite()
+	return true, nil
+}
+
+// OversizeMode determines what happens when a Solid has too many triangles
+// to be written in the STL binary format.
+type OversizeMode int
+
+const (
+	// OversizeError makes writing fail with a *TooManyTrianglesError. This is the default.
+	OversizeError OversizeMode = iota
+
+	// OversizeSplit writes the triangles into several numbered binary files,
+	// each with the maximum allowed number of triangles, except the last one.
+	// For "part.stl" the files are named "part_1.stl", "part_2.stl", and so on.
+	// This only works with Solid.WriteFileWithOptions, Solid.WriteAllWithOptions
+	// fails with a *TooManyTrianglesError.
+	OversizeSplit
+
+	// OversizeASCII writes the solid in the STL ASCII format instead, which
+	// has no limit on the number of triangles.
+	OversizeASCII
+)
+
+// WriteOptions control how a Solid is written by Solid.WriteFileWithOptions
+// and Solid.WriteAllWithOptions. The zero value corresponds to the behaviour
+// of Solid.WriteFile and Solid.WriteAll.
+type WriteOptions struct {
+	// AllowNonFinite allows writing NaN and ±Inf values into binary STL. If
+	// false, a *NonFiniteError is returned before anything is written.
+	AllowNonFinite bool
+
+	// Oversize determines what happens if the solid is to be written in the
+	// binary format, but has too many triangles for it.
+	Oversize OversizeMode
+}
+
 // ReadFile reads the contents of a file into a new Solid object. The file
 // can be either in STL ASCII format, beginning with "solid ", or in
 // STL binary format, beginning with a 84 byte header. Shorthand for os.Open and ReadAll
 func ReadFile(filename string) (solid *Solid, err error) {
+	return ReadFileWithOptions(filename, ReadOptions{})
+}
+
+// ReadFileWithOptions works like ReadFile, using options to control reading.
+func ReadFileWithOptions(filename string, options ReadOptions) (solid *Solid, err error) {
 	var s Solid
-	err = CopyFile(filename, &s)
+	err = CopyFileWithOptions(filename, &s, options)
 	if err == nil {
 		solid = &s
 	}
@@ -33,8 +140,13 @@ func ReadFile(filename string) (solid *Solid, err error) {
 // STL binary format, beginning with a 84 byte header. Because of this,
 // the file pointer has to be at the beginning of the file.
 func ReadAll(r io.ReadSeeker) (solid *Solid, err error) {
+	return ReadAllWithOptions(r, ReadOptions{})
+}
+
+// ReadAllWithOptions works like ReadAll, using options to control reading.
+func ReadAllWithOptions(r io.ReadSeeker, options ReadOptions) (solid *Solid, err error) {
 	var s Solid
-	err = CopyAll(r, &s)
+	err = CopyAllWithOptions(r, &s, options)
 	if err == nil {
 		solid = &s
 	}
@@ -42,12 +154,17 @@ func ReadAll(r io.ReadSeeker) (solid *Solid, err error) {
 }
 
 func CopyFile(filename string, sw Writer) (err error) {
+	return CopyFileWithOptions(filename, sw, ReadOptions{})
+}
+
+// CopyFileWithOptions works like CopyFile, using options to control reading.
+func CopyFileWithOptions(filename string, sw Writer, options ReadOptions) (err error) {
 	file, openErr := os.Open(filename)
 	if openErr != nil {
 		err = openErr
 		return
 	}
-	err = CopyAll(file, sw)
+	err = CopyAllWithOptions(file, sw, options)
 	closeErr := file.Close()
 	if err == nil {
 		err = closeErr
@@ -56,6 +173,11 @@ func CopyFile(filename string, sw Writer) (err error) {
 }
 
 func CopyAll(r io.ReadSeeker, sw Writer) (err error) {
+	return CopyAllWithOptions(r, sw, ReadOptions{})
+}
+
+// CopyAllWithOptions works like CopyAll, using options to control reading.
+func CopyAllWithOptions(r io.ReadSeeker, sw Writer, options ReadOptions) (err error) {
 	isBinary, err := isBinaryFile(r)
 	if err != nil {
 		return
@@ -67,10 +189,10 @@ func CopyAll(r io.ReadSeeker, sw Writer) (err error) {
 
 	if isBinary {
 		sw.SetASCII(false)
-		err = readAllBinary(br, sw)
+		err = readAllBinary(br, sw, &options)
 	} else {
 		sw.SetASCII(true)
-		err = readAllASCII(br, sw)
+		err = readAllASCII(br, sw, &options)
 	}
 
 	return
@@ -100,13 +222,22 @@ func isBinaryFile(r io.ReadSeeker) (isBinary bool, err error) {
 // WriteFile creates file with name filename and write contents of this Solid.
 // Shorthand for os.Create and Solid.WriteAll
 func (s *Solid) WriteFile(filename string) (err error) {
+	return s.WriteFileWithOptions(filename, WriteOptions{})
+}
+
+// WriteFileWithOptions works like WriteFile, using options to control writing.
+func (s *Solid) WriteFileWithOptions(filename string, options WriteOptions) (err error) {
+	if !s.IsAscii && s.exceedsBinaryLimit() && options.Oversize == OversizeSplit {
+		return s.writeSplitFiles(filename, options)
+	}
+
 	file, err := os.Create(filename)
 	if err != nil {
 		return err
 	}
 
 	bufWriter := bufio.NewWriter(file)
-	err = s.WriteAll(bufWriter)
+	err = s.WriteAllWithOptions(bufWriter, options)
 	flushErr := bufWriter.Flush()
 	closeErr := file.Close()
 	if err == nil {
@@ -121,12 +252,51 @@ func (s *Solid) WriteFile(filename string) (err error) {
 // WriteAll writes the contents of this solid to an io.Writer. Depending on solid.IsAscii
 // the STL ASCII format, or the STL binary format is used. If IsAscii
 // is false, and the binary format is used, solid.Name will be used for
-// the header, if solid.BinaryHeader is empty.
+// the header, if solid.BinaryHeader is empty. Triangles containing NaN or ±Inf
+// values are not written in the binary format, a *NonFiniteError is returned
+// instead. The same goes for solids with more triangles than the binary format
+// can hold, they result in a *TooManyTrianglesError.
 func (s *Solid) WriteAll(w io.Writer) error {
-	if s.IsAscii {
+	return s.WriteAllWithOptions(w, WriteOptions{})
+}
+
+// WriteAllWithOptions works like WriteAll, using options to control writing.
+func (s *Solid) WriteAllWithOptions(w io.Writer, options WriteOptions) error {
+	if s.IsAscii || (s.exceedsBinaryLimit() && options.Oversize == OversizeASCII) {
 		return writeSolidASCII(w, s)
 	}
-	return writeSolidBinary(w, s)
+	return writeSolidBinary(w, s, &options)
+}
+
+// writeSplitFiles writes s into numbered binary files derived from filename,
+// each holding at most maxBinaryTriangleCount triangles. Non-finite values
+// are detected before any file is created.
+func (s *Solid) writeSplitFiles(filename string, options WriteOptions) error {
+	if !options.AllowNonFinite {
+		if i := s.firstNonFinite(); i >= 0 {
+			return &NonFiniteError{TriangleIndex: i}
+		}
+	}
+	ext := filepath.Ext(filename)
+	base := filename[:len(filename)-len(ext)]
+	options.Oversize = OversizeError
+	part := Solid{
+		BinaryHeader: s.BinaryHeader,
+		Name:         s.Name,
+	}
+	n := uint64(len(s.Triangles))
+	for i, start := 1, uint64(0); start < n; i, start = i+1, start+maxBinaryTriangleCount {
+		end := start + maxBinaryTriangleCount
+		if end > n {
+			end = n
+		}
+		part.Triangles = s.Triangles[start:end]
+		err := part.WriteFileWithOptions(fmt.Sprintf("%s_%d%s", base, i, ext), options)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Extracts an ASCII string from a byte slice. Reads all characters
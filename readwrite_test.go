@@ -3,8 +3,12 @@ package stl
 // Tests for reading and writing STL files.
 
 import (
+	"bytes"
 	"io/ioutil"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
 	"testing"
 )
@@ -262,3 +266,161 @@ func BenchmarkWriteMediumFile_Binary(b *testing.B) {
 		}
 	}
 }
+
+func makeNonFiniteTestSolid() *Solid {
+	s := makeTestSolid()
+	s.Triangles[1].Vertices[2][0] = float32(math.NaN())
+	s.Triangles[3].Normal[1] = float32(math.Inf(-1))
+	return s
+}
+
+func TestWriteAll_NonFinite(t *testing.T) {
+	s := makeNonFiniteTestSolid()
+	s.IsAscii = false
+	var buf bytes.Buffer
+	err := s.WriteAll(&buf)
+	nfErr, ok := err.(*NonFiniteError)
+	if !ok {
+		t.Fatalf("expected *NonFiniteError, got %v", err)
+	}
+	if nfErr.TriangleIndex != 1 {
+		t.Errorf("expected triangle index 1, got %d", nfErr.TriangleIndex)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %d bytes", buf.Len())
+	}
+
+	err = s.WriteAllWithOptions(&buf, WriteOptions{AllowNonFinite: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadAllWithOptions_NonFinite(t *testing.T) {
+	s := makeNonFiniteTestSolid()
+	for _, isASCII := range []bool{false, true} {
+		s.IsAscii = isASCII
+		var buf bytes.Buffer
+		if err := s.WriteAllWithOptions(&buf, WriteOptions{AllowNonFinite: true}); err != nil {
+			t.Fatal(err)
+		}
+		data := buf.Bytes()
+
+		keep, err := ReadAll(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("ascii=%v: %s", isASCII, err)
+		}
+		if got := keep.CheckFinite(); !reflect.DeepEqual(got, []int{1, 3}) {
+			t.Errorf("ascii=%v: CheckFinite returned %v, expected [1 3]", isASCII, got)
+		}
+
+		_, err = ReadAllWithOptions(bytes.NewReader(data), ReadOptions{NonFinite: NonFiniteReject})
+		if nfErr, ok := err.(*NonFiniteError); !ok || nfErr.TriangleIndex != 1 {
+			t.Errorf("ascii=%v: expected *NonFiniteError for triangle 1, got %v", isASCII, err)
+		}
+
+		dropped, err := ReadAllWithOptions(bytes.NewReader(data), ReadOptions{NonFinite: NonFiniteDrop})
+		if err != nil {
+			t.Fatalf("ascii=%v: %s", isASCII, err)
+		}
+		if len(dropped.Triangles) != 2 {
+			t.Errorf("ascii=%v: expected 2 triangles after dropping, got %d", isASCII, len(dropped.Triangles))
+		}
+
+		zeroed, err := ReadAllWithOptions(bytes.NewReader(data), ReadOptions{NonFinite: NonFiniteZero})
+		if err != nil {
+			t.Fatalf("ascii=%v: %s", isASCII, err)
+		}
+		if len(zeroed.Triangles) != 4 || zeroed.CheckFinite() != nil {
+			t.Errorf("ascii=%v: expected 4 finite triangles after zeroing", isASCII)
+		}
+		if zeroed.Triangles[1].Vertices[2] != (Vec3{0, 0, 1}) {
+			t.Errorf("ascii=%v: expected zeroed vertex [0 0 1], got %v", isASCII, zeroed.Triangles[1].Vertices[2])
+		}
+	}
+}
+
+func TestWriteAll_TooManyTriangles(t *testing.T) {
+	defer func(orig uint64) { maxBinaryTriangleCount = orig }(maxBinaryTriangleCount)
+	maxBinaryTriangleCount = 3
+
+	s := makeTestSolid()
+	s.IsAscii = false
+	var buf bytes.Buffer
+	err := s.WriteAll(&buf)
+	if tmErr, ok := err.(*TooManyTrianglesError); !ok || tmErr.Count != 4 {
+		t.Errorf("expected *TooManyTrianglesError with count 4, got %v", err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("expected no output, got %d bytes", buf.Len())
+	}
+
+	err = s.WriteAllWithOptions(&buf, WriteOptions{Oversize: OversizeASCII})
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := ReadAll(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !read.IsAscii || len(read.Triangles) != 4 {
+		t.Errorf("expected ASCII fallback with 4 triangles, got IsAscii=%v with %d triangles", read.IsAscii, len(read.Triangles))
+	}
+}
+
+func TestWriteFileWithOptions_OversizeSplit(t *testing.T) {
+	defer func(orig uint64) { maxBinaryTriangleCount = orig }(maxBinaryTriangleCount)
+	maxBinaryTriangleCount = 3
+
+	tmpDirName, tmpErr := ioutil.TempDir(os.TempDir(), "stl_test")
+	if tmpErr != nil {
+		t.Fatal(tmpErr.Error())
+	}
+	defer os.RemoveAll(tmpDirName)
+
+	s := makeTestSolid()
+	s.IsAscii = false
+	err := s.WriteFileWithOptions(filepath.Join(tmpDirName, "part.stl"), WriteOptions{Oversize: OversizeSplit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var triangles []Triangle
+	for i, expected := range []int{3, 1} {
+		part, err := ReadFile(filepath.Join(tmpDirName, "part_"+strconv.Itoa(i+1)+".stl"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(part.Triangles) != expected {
+			t.Errorf("part %d: expected %d triangles, got %d", i+1, expected, len(part.Triangles))
+		}
+		triangles = append(triangles, part.Triangles...)
+	}
+	if !reflect.DeepEqual(triangles, s.Triangles) {
+		t.Error("split files do not contain the original triangles")
+	}
+	if _, err := os.Stat(filepath.Join(tmpDirName, "part.stl")); !os.IsNotExist(err) {
+		t.Error("unsplit file part.stl should not exist")
+	}
+}
+
+func TestWriteFileWithOptions_OversizeSplitNonFinite(t *testing.T) {
+	defer func(orig uint64) { maxBinaryTriangleCount = orig }(maxBinaryTriangleCount)
+	maxBinaryTriangleCount = 3
+
+	tmpDirName, tmpErr := ioutil.TempDir(os.TempDir(), "stl_test")
+	if tmpErr != nil {
+		t.Fatal(tmpErr.Error())
+	}
+	defer os.RemoveAll(tmpDirName)
+
+	s := makeTestSolid()
+	s.IsAscii = false
+	s.Triangles[3].Vertices[1][0] = float32(math.NaN())
+	err := s.WriteFileWithOptions(filepath.Join(tmpDirName, "part.stl"), WriteOptions{Oversize: OversizeSplit})
+	if nfErr, ok := err.(*NonFiniteError); !ok || nfErr.TriangleIndex != 3 {
+		t.Fatalf("expected NonFiniteError for triangle 3, got %v", err)
+	}
+	if files, _ := ioutil.ReadDir(tmpDirName); len(files) != 0 {
+		t.Errorf("expected no files to be written, got %d", len(files))
+	}
+}
@@ -0,0 +1,149 @@
+package stl
+
+// This file contains the automatic repair of common mesh errors
+
+// RepairOptions control Solid.Repair.
+type RepairOptions struct {
+	// WeldTolerance is the maximum difference per coordinate up to which
+	// vertices are merged, like in IndexedMesh.Weld. The default 0 only
+	// merges vertices that are exactly equal.
+	WeldTolerance float32
+
+	// SliverTolerance determines which triangles are removed as slivers: those
+	// with an area of at most SliverTolerance times the square of their longest
+	// edge. The default 0 only removes triangles with zero area.
+	SliverTolerance float64
+}
+
+// RepairReport describes the changes made by Solid.Repair.
+type RepairReport struct {
+	// WeldedVertices is the number of distinct vertices merged into other ones.
+	WeldedVertices int
+
+	// RemovedDegenerate is the number of triangles removed because they had
+	// equal vertices, or were slivers.
+	RemovedDegenerate int
+
+	// RemovedDuplicates is the number of triangles removed because another
+	// triangle had the same vertices in the same order.
+	RemovedDuplicates int
+
+	// RemovedInternal is the number of triangles removed in pairs with the
+	// same vertices in opposite order, like the faces between two shells
+	// touching each other.
+	RemovedInternal int
+
+	// FlippedTriangles is the number of triangles whose vertex order was
+	// reversed to match their neighbors, or to make their shell face outwards.
+	FlippedTriangles int
+
+	// RecalculatedNormals is the number of triangles whose normal vector did
+	// not match their vertices, and was recalculated.
+	RecalculatedNormals int
+}
+
+// Repair tries to fix the errors reported by Validate. It
+//   - welds vertices that are within options.WeldTolerance of each other,
+//   - removes triangles with equal vertices, and slivers with (almost) no area,
+//   - removes triangles using the same vertices as an earlier triangle in
+//     the same order,
+//   - removes pairs of triangles using the same vertices in opposite order,
+//     so that shells touching each other are joined,
+//   - flips the vertex order of triangles, so that every edge shared by two
+//     triangles is used in opposite directions, and every shell faces outwards,
+//     see OrientConsistently,
+//   - recalculates normal vectors that do not match the vertices.
+//
+// The order of the remaining triangles and their attributes are kept.
+func (s *Solid) Repair(options RepairOptions) RepairReport {
+	var report RepairReport
+	m := NewIndexedMesh(s, 0)
+	report.WeldedVertices = m.Weld(options.WeldTolerance)
+
+	keep := make([]bool, len(m.Faces))
+	seen := make(map[[3]uint32]bool, len(m.Faces))
+	unpaired := make(map[[3]uint32][]int)
+	for f, face := range m.Faces {
+		if m.isSliverFace(f, options.SliverTolerance) {
+			report.RemovedDegenerate++
+			continue
+		}
+		key := rotatedFace(face)
+		if seen[key] {
+			report.RemovedDuplicates++
+			continue
+		}
+		seen[key] = true
+		reversed := rotatedFace([3]uint32{face[0], face[2], face[1]})
+		if others := unpaired[reversed]; len(others) > 0 {
+			keep[others[len(others)-1]] = false
+			unpaired[reversed] = others[:len(others)-1]
+			report.RemovedInternal += 2
+			continue
+		}
+		unpaired[key] = append(unpaired[key], f)
+		keep[f] = true
+	}
+	m.filterFaces(keep)
+
+	for f, flip := range m.orientation() {
+		if flip {
+			m.flipFace(f)
+			report.FlippedTriangles++
+		}
+	}
+
+	triangles := m.Solid().Triangles
+	for i := range triangles {
+		if !triangles[i].checkNormal(normalAngleTolerance) {
+			triangles[i].recalculateNormal()
+			report.RecalculatedNormals++
+		}
+	}
+	s.Triangles = triangles
+	return report
+}
+
+// isSliverFace returns true if face f refers to the same vertex more
+// than once, or its area is at most sliverTolerance times the square of
+// its longest edge.
+func (m *IndexedMesh) isSliverFace(f int, sliverTolerance float64) bool {
+	face := m.Faces[f]
+	if face[0] == face[1] || face[0] == face[2] || face[1] == face[2] {
+		return true
+	}
+	a := toVec64(m.Vertices[face[0]])
+	ab := toVec64(m.Vertices[face[1]]).sub(a)
+	ac := toVec64(m.Vertices[face[2]]).sub(a)
+	bc := ac.sub(ab)
+	longest := ab.dot(ab)
+	if l := ac.dot(ac); l > longest {
+		longest = l
+	}
+	if l := bc.dot(bc); l > longest {
+		longest = l
+	}
+	return 0.5*ab.cross(ac).len() <= sliverTolerance*longest
+}
+
+// filterFaces removes all faces f with keep[f] == false.
+func (m *IndexedMesh) filterFaces(keep []bool) {
+	n := 0
+	for f := range m.Faces {
+		if keep[f] {
+			m.Faces[n] = m.Faces[f]
+			m.Normals[n] = m.Normals[f]
+			m.Attributes[n] = m.Attributes[f]
+			n++
+		}
+	}
+	m.Faces = m.Faces[:n]
+	m.Normals = m.Normals[:n]
+	m.Attributes = m.Attributes[:n]
+}
+
+// flipFace reverses the vertex order of face f, and its normal vector.
+func (m *IndexedMesh) flipFace(f int) {
+	m.Faces[f][1], m.Faces[f][2] = m.Faces[f][2], m.Faces[f][1]
+	m.Normals[f] = m.Normals[f].MultScalar(-1)
+}
@@ -0,0 +1,99 @@
+package stl
+
+// Tests for the automatic repair of solids
+
+import (
+	"testing"
+)
+
+func TestRepair(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	for i := range s.Triangles {
+		s.Triangles[i].Attributes = uint16(i)
+	}
+	// vertex slightly off
+	s.Triangles[3].Vertices[0][2] += 1e-5
+	// wrong orientation, its normal is flipped with it
+	s.Triangles[6].Vertices[1], s.Triangles[6].Vertices[2] = s.Triangles[6].Vertices[2], s.Triangles[6].Vertices[1]
+	s.Triangles[6].recalculateNormal()
+	// wrong normal
+	s.Triangles[7].Normal = Vec3{0, 0, 1}
+	// duplicate
+	s.Triangles = append(s.Triangles, s.Triangles[0])
+	// degenerate and sliver
+	s.Triangles = append(s.Triangles,
+		Triangle{Vertices: [3]Vec3{{0, 0, 0}, {0, 0, 0}, {1, 0, 0}}},
+		Triangle{Vertices: [3]Vec3{{0, 0, 0}, {0.5, 0, 0}, {1, 0, 0}}})
+
+	if errors := s.Validate(); len(errors) == 0 {
+		t.Fatal("broken test solid is valid")
+	}
+
+	report := s.Repair(RepairOptions{WeldTolerance: 1e-4})
+	expected := RepairReport{
+		WeldedVertices:      1,
+		RemovedDegenerate:   2,
+		RemovedDuplicates:   1,
+		FlippedTriangles:    1,
+		RecalculatedNormals: 1,
+	}
+	if report != expected {
+		t.Errorf("expected report %+v, got %+v", expected, report)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("repaired solid is not valid: %v", errors)
+	}
+	if len(s.Triangles) != 12 {
+		t.Fatalf("expected 12 triangles, got %d", len(s.Triangles))
+	}
+	for i := range s.Triangles {
+		if s.Triangles[i].Attributes != uint16(i) {
+			t.Errorf("triangle %d has attributes %d", i, s.Triangles[i].Attributes)
+		}
+	}
+	if v := s.Volume(); !almostEqual64(v, 1, 1e-6) {
+		t.Errorf("expected volume 1, got %g", v)
+	}
+}
+
+func TestRepairCavity(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 3)
+	// part of the outer shell inside-out
+	for i := 0; i < 6; i++ {
+		vs := &s.Triangles[i].Vertices
+		vs[1], vs[2] = vs[2], vs[1]
+		s.Triangles[i].recalculateNormal()
+	}
+	// the cavity is wrongly facing outwards
+	s.Triangles = append(s.Triangles, makeTestCube(Vec3{1, 1, 1}, 1).Triangles...)
+
+	report := s.Repair(RepairOptions{})
+	if expected := (RepairReport{FlippedTriangles: 6 + 12}); report != expected {
+		t.Errorf("expected report %+v, got %+v", expected, report)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("repaired solid is not valid: %v", errors)
+	}
+	if v := s.Volume(); !almostEqual64(v, 27-1, 1e-6) {
+		t.Errorf("expected volume 26, got %g", v)
+	}
+}
+
+func TestRepairTouchingShells(t *testing.T) {
+	// two cubes sharing a wall, whose triangles have opposite orientation
+	s := Merge(makeTestCube(Vec3{0, 0, 0}, 1), makeTestCube(Vec3{1, 0, 0}, 1))
+	if errors := s.Validate(); len(errors) == 0 {
+		t.Fatal("touching cubes are valid")
+	}
+
+	report := s.Repair(RepairOptions{})
+	if expected := (RepairReport{RemovedInternal: 4}); report != expected {
+		t.Errorf("expected report %+v, got %+v", expected, report)
+	}
+	if errors := s.Validate(); len(errors) != 0 {
+		t.Errorf("repaired solid is not valid: %v", errors)
+	}
+	if v := s.Volume(); !almostEqual64(v, 2, 1e-6) {
+		t.Errorf("expected volume 2, got %g", v)
+	}
+}
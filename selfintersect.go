@@ -0,0 +1,375 @@
+package stl
+
+// This file contains the detection of self-intersecting triangles
+
+import (
+	"math"
+	"sort"
+)
+
+// SelfIntersections returns all pairs of indices of triangles in s.Triangles
+// that intersect or touch each other, with the smaller index first, sorted
+// ascending. Triangles sharing an edge or a vertex are only reported if they
+// also have other points in common, e.g. if they overlap in the same plane.
+// Triangles with equal vertices or zero area are ignored, as they are already
+// reported by Validate. Candidate pairs are found using a BVH, so only
+// triangles with overlapping bounding boxes are tested.
+func (s *Solid) SelfIntersections() [][2]int {
+	var pairs [][2]int
+	s.SelfIntersectionsFunc(func(i, j int) bool {
+		pairs = append(pairs, [2]int{i, j})
+		return true
+	})
+	sort.Slice(pairs, func(a, b int) bool {
+		if pairs[a][0] != pairs[b][0] {
+			return pairs[a][0] < pairs[b][0]
+		}
+		return pairs[a][1] < pairs[b][1]
+	})
+	return pairs
+}
+
+// SelfIntersectionsFunc calls fn for every pair of intersecting triangles
+// found by SelfIntersections, as soon as it is found, with i < j. The pairs
+// are ordered by i, but not by j. If fn returns false, the search stops.
+func (s *Solid) SelfIntersectionsFunc(fn func(i, j int) bool) {
+	n := len(s.Triangles)
+	triangles := make([]triangle64, n)
+	boxes := make([]box64, n)
+	var valid []int
+	for i := range s.Triangles {
+		t := &s.Triangles[i]
+		for v := 0; v < 3; v++ {
+			triangles[i][v] = toVec64(t.Vertices[v])
+		}
+		if t.hasEqualVertices() || triangles[i].normal() == (vec64{}) || !t.isFinite() {
+			continue
+		}
+		valid = append(valid, i)
+		boxes[i] = triangles[i].box()
+	}
+	if len(valid) < 2 {
+		return
+	}
+
+	// the BVH reorders its indices, so valid is kept for the outer loop
+	bvh := newBVH(triangles, append([]int(nil), valid...), BVHOptions{})
+	for _, i := range valid {
+		stop := false
+		bvh.traverse(func(box *box64) bool {
+			return box.overlaps(boxes[i])
+		}, func(j int) bool {
+			if j <= i || !boxes[i].overlaps(boxes[j]) {
+				return true
+			}
+			if trianglesIntersect(&triangles[i], &triangles[j]) && !fn(i, j) {
+				stop = true
+				return false
+			}
+			return true
+		})
+		if stop {
+			return
+		}
+	}
+}
+
+// triangle64 is a triangle in double precision.
+type triangle64 [3]vec64
+
+// normal returns the cross product of two edges, i.e. a normal vector with
+// twice the triangle's area as length.
+func (t *triangle64) normal() vec64 {
+	return t[1].sub(t[0]).cross(t[2].sub(t[0]))
+}
+
+// box returns the axis aligned bounding box of t.
+func (t *triangle64) box() box64 {
+	b := box64{t[0], t[0]}
+	b.extend(t[1])
+	b.extend(t[2])
+	return b
+}
+
+// box64 is an axis aligned box in double precision.
+type box64 struct {
+	min, max vec64
+}
+
+// extend grows b so that it contains p.
+func (b *box64) extend(p vec64) {
+	for d := 0; d < 3; d++ {
+		b.min[d] = math.Min(b.min[d], p[d])
+		b.max[d] = math.Max(b.max[d], p[d])
+	}
+}
+
+// maxExtent returns the largest edge length of b.
+func (b *box64) maxExtent() float64 {
+	return math.Max(b.max[0]-b.min[0], math.Max(b.max[1]-b.min[1], b.max[2]-b.min[2]))
+}
+
+// overlaps returns true if b and o have at least one point in common.
+func (b *box64) overlaps(o box64) bool {
+	for d := 0; d < 3; d++ {
+		if b.min[d] > o.max[d] || o.min[d] > b.max[d] {
+			return false
+		}
+	}
+	return true
+}
+
+// trianglesIntersect returns true if the non-degenerate triangles a and b
+// have points in common, apart from shared vertices and edges.
+func trianglesIntersect(a, b *triangle64) bool {
+	var sharedA, sharedB [3]bool
+	shared := 0
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if a[i] == b[j] {
+				sharedA[i], sharedB[j] = true, true
+				shared++
+			}
+		}
+	}
+	if shared >= 3 {
+		// the same triangle twice
+		return true
+	}
+
+	na, nb := a.normal(), b.normal()
+	if a.isCoplanar(b, na) {
+		return coplanarTrianglesIntersect(a, b, na, sharedA, sharedB, shared)
+	}
+
+	switch shared {
+	case 2:
+		// the only other common points are on the shared edge
+		return false
+	case 1:
+		// only the edges opposite of the shared vertex can touch the other triangle
+		return segmentIntersectsTriangle(oppositeEdge(a, sharedA), b, nb) ||
+			segmentIntersectsTriangle(oppositeEdge(b, sharedB), a, na)
+	}
+
+	if a.allOnOneSide(b, na) || b.allOnOneSide(a, nb) {
+		return false
+	}
+	for i := 0; i < 3; i++ {
+		if segmentIntersectsTriangle([2]vec64{a[i], a[(i+1)%3]}, b, nb) ||
+			segmentIntersectsTriangle([2]vec64{b[i], b[(i+1)%3]}, a, na) {
+			return true
+		}
+	}
+	return false
+}
+
+// planeTolerance returns the tolerance for the distance of a point from the
+// plane of t, multiplied by the length of its normal n, as calculated by orient.
+func (t *triangle64) planeTolerance(n vec64) float64 {
+	b := t.box()
+	scale := math.Max(b.maxExtent(), math.Max(math.Abs(b.min.dot(vec64{1, 1, 1})), math.Abs(b.max.dot(vec64{1, 1, 1}))))
+	return 1e-12 * n.len() * scale
+}
+
+// orient returns the distance of p from the plane of t, multiplied by the
+// length of the normal n of t. It is positive on the side n points to.
+func (t *triangle64) orient(p vec64, n vec64) float64 {
+	return n.dot(p.sub(t[0]))
+}
+
+// isCoplanar returns true if all vertices of o lie in the plane of t with normal n.
+func (t *triangle64) isCoplanar(o *triangle64, n vec64) bool {
+	tol := t.planeTolerance(n)
+	for _, p := range o {
+		if math.Abs(t.orient(p, n)) > tol {
+			return false
+		}
+	}
+	return true
+}
+
+// allOnOneSide returns true if all vertices of o lie strictly on the same
+// side of the plane of t with normal n.
+func (t *triangle64) allOnOneSide(o *triangle64, n vec64) bool {
+	tol := t.planeTolerance(n)
+	d0, d1, d2 := t.orient(o[0], n), t.orient(o[1], n), t.orient(o[2], n)
+	return (d0 > tol && d1 > tol && d2 > tol) || (d0 < -tol && d1 < -tol && d2 < -tol)
+}
+
+// oppositeEdge returns the edge of t connecting the two vertices not marked in shared.
+func oppositeEdge(t *triangle64, shared [3]bool) [2]vec64 {
+	var e [2]vec64
+	n := 0
+	for i := 0; i < 3 && n < 2; i++ {
+		if !shared[i] {
+			e[n] = t[i]
+			n++
+		}
+	}
+	return e
+}
+
+// segmentIntersectsTriangle returns true if the segment s has at least one
+// point in common with triangle t, which has the normal n.
+func segmentIntersectsTriangle(s [2]vec64, t *triangle64, n vec64) bool {
+	tol := t.planeTolerance(n)
+	d0, d1 := t.orient(s[0], n), t.orient(s[1], n)
+	if (d0 > tol && d1 > tol) || (d0 < -tol && d1 < -tol) {
+		return false
+	}
+	if math.Abs(d0) <= tol && math.Abs(d1) <= tol {
+		// segment within the plane
+		axis := dominantAxis(n)
+		p, q := project2(s[0], axis), project2(s[1], axis)
+		a, b, c := project2(t[0], axis), project2(t[1], axis), project2(t[2], axis)
+		return pointInTriangle2Any(p, a, b, c) ||
+			segmentsIntersect2(p, q, a, b) || segmentsIntersect2(p, q, b, c) || segmentsIntersect2(p, q, c, a)
+	}
+	var x vec64
+	switch {
+	case math.Abs(d0) <= tol:
+		x = s[0]
+	case math.Abs(d1) <= tol:
+		x = s[1]
+	default:
+		x = s[0].add(s[1].sub(s[0]).scale(d0 / (d0 - d1)))
+	}
+	// x lies in the plane, check whether it is inside the triangle
+	edgeTol := -1e-12 * n.dot(n)
+	for i := 0; i < 3; i++ {
+		e := t[(i+1)%3].sub(t[i])
+		if e.cross(x.sub(t[i])).dot(n) < edgeTol {
+			return false
+		}
+	}
+	return true
+}
+
+// coplanarTrianglesIntersect checks whether the triangles a and b lying in the
+// same plane with normal n overlap, apart from shared vertices and edges.
+func coplanarTrianglesIntersect(a, b *triangle64, n vec64, sharedA, sharedB [3]bool, shared int) bool {
+	axis := dominantAxis(n)
+	var pa, pb [3][2]float64
+	for i := 0; i < 3; i++ {
+		pa[i], pb[i] = project2(a[i], axis), project2(b[i], axis)
+	}
+
+	switch shared {
+	case 2:
+		// they overlap, if the vertices not on the shared edge are on the same side of it
+		var edge [2][2]float64
+		var otherA, otherB [2]float64
+		k := 0
+		for i := 0; i < 3; i++ {
+			if sharedA[i] {
+				edge[k] = pa[i]
+				k++
+			} else {
+				otherA = pa[i]
+			}
+			if !sharedB[i] {
+				otherB = pb[i]
+			}
+		}
+		return cross2(edge[0], edge[1], otherA)*cross2(edge[0], edge[1], otherB) > 0
+	case 1:
+		// they overlap, if the corners at the shared vertex overlap
+		var ia, ib int
+		for i := 0; i < 3; i++ {
+			if sharedA[i] {
+				ia = i
+			}
+			if sharedB[i] {
+				ib = i
+			}
+		}
+		return cornersOverlap(pa, ia, pb, ib)
+	}
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			if segmentsIntersect2(pa[i], pa[(i+1)%3], pb[j], pb[(j+1)%3]) {
+				return true
+			}
+		}
+	}
+	return pointInTriangle2Any(pa[0], pb[0], pb[1], pb[2]) || pointInTriangle2Any(pb[0], pa[0], pa[1], pa[2])
+}
+
+// cornersOverlap returns true if the corner of triangle a at vertex ia, and
+// the corner of triangle b at vertex ib, which is at the same point, overlap.
+func cornersOverlap(a [3][2]float64, ia int, b [3][2]float64, ib int) bool {
+	v := a[ia]
+	a1, a2 := sub2(a[(ia+1)%3], v), sub2(a[(ia+2)%3], v)
+	b1, b2 := sub2(b[(ib+1)%3], v), sub2(b[(ib+2)%3], v)
+	return strictlyInCorner(b1, a1, a2) || strictlyInCorner(b2, a1, a2) ||
+		strictlyInCorner(add2(b1, b2), a1, a2) ||
+		strictlyInCorner(a1, b1, b2) || strictlyInCorner(a2, b1, b2) ||
+		strictlyInCorner(add2(a1, a2), b1, b2)
+}
+
+// strictlyInCorner returns true if direction d lies strictly between the
+// directions c1 and c2, which enclose an angle of less than 180 degrees.
+func strictlyInCorner(d, c1, c2 [2]float64) bool {
+	o := det2(c1, c2)
+	return det2(c1, d)*o > 0 && det2(d, c2)*o > 0
+}
+
+// dominantAxis returns the index of the largest absolute component of n.
+func dominantAxis(n vec64) int {
+	if math.Abs(n[0]) >= math.Abs(n[1]) && math.Abs(n[0]) >= math.Abs(n[2]) {
+		return 0
+	}
+	if math.Abs(n[1]) >= math.Abs(n[2]) {
+		return 1
+	}
+	return 2
+}
+
+// project2 projects p onto the coordinate plane orthogonal to axis.
+func project2(p vec64, axis int) [2]float64 {
+	return [2]float64{p[(axis+1)%3], p[(axis+2)%3]}
+}
+
+func sub2(a, b [2]float64) [2]float64 {
+	return [2]float64{a[0] - b[0], a[1] - b[1]}
+}
+
+func add2(a, b [2]float64) [2]float64 {
+	return [2]float64{a[0] + b[0], a[1] + b[1]}
+}
+
+// det2 returns the determinant of the 2x2 matrix with columns a and b.
+func det2(a, b [2]float64) float64 {
+	return a[0]*b[1] - a[1]*b[0]
+}
+
+// pointInTriangle2Any returns true if p lies within or on the border of the
+// triangle a, b, c of any orientation.
+func pointInTriangle2Any(p, a, b, c [2]float64) bool {
+	d1, d2, d3 := cross2(a, b, p), cross2(b, c, p), cross2(c, a, p)
+	hasNeg := d1 < 0 || d2 < 0 || d3 < 0
+	hasPos := d1 > 0 || d2 > 0 || d3 > 0
+	return !(hasNeg && hasPos)
+}
+
+// segmentsIntersect2 returns true if the closed segments p1-p2 and q1-q2 have
+// at least one point in common.
+func segmentsIntersect2(p1, p2, q1, q2 [2]float64) bool {
+	d1 := cross2(q1, q2, p1)
+	d2 := cross2(q1, q2, p2)
+	d3 := cross2(p1, p2, q1)
+	d4 := cross2(p1, p2, q2)
+	if ((d1 > 0 && d2 < 0) || (d1 < 0 && d2 > 0)) && ((d3 > 0 && d4 < 0) || (d3 < 0 && d4 > 0)) {
+		return true
+	}
+	return (d1 == 0 && onSegment2(q1, q2, p1)) || (d2 == 0 && onSegment2(q1, q2, p2)) ||
+		(d3 == 0 && onSegment2(p1, p2, q1)) || (d4 == 0 && onSegment2(p1, p2, q2))
+}
+
+// onSegment2 returns true if p, which is collinear with a and b, lies between them.
+func onSegment2(a, b, p [2]float64) bool {
+	return math.Min(a[0], b[0]) <= p[0] && p[0] <= math.Max(a[0], b[0]) &&
+		math.Min(a[1], b[1]) <= p[1] && p[1] <= math.Max(a[1], b[1])
+}
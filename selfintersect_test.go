@@ -0,0 +1,120 @@
+package stl
+
+// Tests for the detection of self-intersecting triangles
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSelfIntersectionsNone(t *testing.T) {
+	for name, s := range map[string]*Solid{
+		"tetrahedron": makeTestSolid(),
+		"cube":        makeTestCube(Vec3{0, 0, 0}, 1),
+		"torus":       makeTestTorus(2, 0.5, 24, 12),
+	} {
+		if pairs := s.SelfIntersections(); len(pairs) != 0 {
+			t.Errorf("%s: expected no intersections, got %v", name, pairs)
+		}
+	}
+
+	// two cubes touching in a corner only share a vertex
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = append(s.Triangles, makeTestCube(Vec3{1, 1, 1}, 1).Triangles...)
+	if pairs := s.SelfIntersections(); len(pairs) != 0 {
+		t.Errorf("cubes touching in a corner: expected no intersections, got %v", pairs)
+	}
+}
+
+func TestSelfIntersectionsOverlappingCubes(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = append(s.Triangles, makeTestCube(Vec3{0.5, 0.5, 0.5}, 1).Triangles...)
+	pairs := s.SelfIntersections()
+	if len(pairs) == 0 {
+		t.Fatal("expected intersections of overlapping cubes")
+	}
+	for _, p := range pairs {
+		if p[0] >= 12 || p[1] < 12 {
+			t.Errorf("unexpected intersection of triangles of the same cube: %v", p)
+		}
+	}
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i-1][0] > pairs[i][0] || (pairs[i-1][0] == pairs[i][0] && pairs[i-1][1] >= pairs[i][1]) {
+			t.Errorf("pairs not sorted: %v", pairs)
+		}
+	}
+
+	// the streaming variant finds the same pairs, and can be stopped
+	count := 0
+	s.SelfIntersectionsFunc(func(i, j int) bool {
+		count++
+		return true
+	})
+	if count != len(pairs) {
+		t.Errorf("SelfIntersectionsFunc found %d pairs, expected %d", count, len(pairs))
+	}
+	count = 0
+	s.SelfIntersectionsFunc(func(i, j int) bool {
+		count++
+		return false
+	})
+	if count != 1 {
+		t.Errorf("expected search to stop after 1 pair, got %d", count)
+	}
+}
+
+func TestSelfIntersectionsSharedVertices(t *testing.T) {
+	tri := func(a, b, c Vec3) Triangle {
+		t := Triangle{Vertices: [3]Vec3{a, b, c}}
+		t.recalculateNormal()
+		return t
+	}
+	o := Vec3{0, 0, 0}
+	for _, test := range []struct {
+		name      string
+		triangles []Triangle
+		expected  [][2]int
+	}{
+		{"duplicate", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(Vec3{0, 1, 0}, o, Vec3{1, 0, 0})}, [][2]int{{0, 1}}},
+		{"folded edge", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{1, 0, 0}, Vec3{0.5, 0.5, 0})}, [][2]int{{0, 1}}},
+		{"flat edge", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{0, -1, 0}, Vec3{1, 0, 0})}, nil},
+		{"overlapping corner", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{1, 1, 0}, Vec3{-1, 1, 0})}, [][2]int{{0, 1}}},
+		{"separate corner", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{-1, 0, 0}, Vec3{0, -1, 0})}, nil},
+		{"piercing corner", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{1, 1, 1}, Vec3{1, 1, -1})}, [][2]int{{0, 1}}},
+		{"touching corner", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(o, Vec3{0, 0, 1}, Vec3{-1, 0, 1})}, nil},
+		{"touching face", []Triangle{tri(o, Vec3{2, 0, 0}, Vec3{0, 2, 0}), tri(Vec3{0.5, 0.5, 0}, Vec3{1, 0.5, 1}, Vec3{0.5, 1, 1})}, [][2]int{{0, 1}}},
+		{"in plane inside", []Triangle{tri(o, Vec3{2, 0, 0}, Vec3{0, 2, 0}), tri(Vec3{0.5, 0.5, 0}, Vec3{0.5, 0.2, 0}, Vec3{0.2, 0.5, 0})}, [][2]int{{0, 1}}},
+		{"parallel", []Triangle{tri(o, Vec3{1, 0, 0}, Vec3{0, 1, 0}), tri(Vec3{0, 0, 1}, Vec3{1, 0, 1}, Vec3{0, 1, 1})}, nil},
+	} {
+		s := &Solid{Triangles: test.triangles}
+		if pairs := s.SelfIntersections(); !reflect.DeepEqual(pairs, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, pairs)
+		}
+	}
+}
+
+func TestSelfIntersectionsLargeAndSmall(t *testing.T) {
+	tri := func(a, b, c Vec3) Triangle {
+		t := Triangle{Vertices: [3]Vec3{a, b, c}}
+		t.recalculateNormal()
+		return t
+	}
+	// a large square, with many tiny triangles above it
+	s := &Solid{Triangles: []Triangle{
+		tri(Vec3{0, 0, 0}, Vec3{1000, 0, 0}, Vec3{1000, 1000, 0}),
+		tri(Vec3{0, 0, 0}, Vec3{1000, 1000, 0}, Vec3{0, 1000, 0}),
+	}}
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 100; y++ {
+			p := Vec3{5 * float32(x), 10 * float32(y), 1}
+			s.Triangles = append(s.Triangles, tri(p, p.Add(Vec3{0.01, 0, 0}), p.Add(Vec3{0, 0.01, 0})))
+		}
+	}
+	// one of them piercing the square
+	s.Triangles = append(s.Triangles, tri(Vec3{700, 200, -0.01}, Vec3{700.01, 200, 0.01}, Vec3{700, 200.01, 0.01}))
+
+	expected := [][2]int{{0, len(s.Triangles) - 1}}
+	if pairs := s.SelfIntersections(); !reflect.DeepEqual(pairs, expected) {
+		t.Errorf("expected %v, got %v", expected, pairs)
+	}
+}
@@ -0,0 +1,336 @@
+package stl
+
+// This file contains planar slicing of solids into layer contours
+
+import (
+	"math"
+	"sort"
+)
+
+// Layer is the cross-section of a solid with a plane, as returned by Solid.Slice.
+type Layer struct {
+	// Normal is the unit normal vector of the plane.
+	Normal Vec3
+
+	// Height is the distance of the plane from the origin along Normal,
+	// i.e. the plane contains all points p with Normal.Dot(p) == Height.
+	Height float64
+
+	// Contours are the closed polygons where the plane cuts the solid.
+	Contours []Contour
+
+	// OpenContours are polylines that could not be closed, because the solid
+	// is not watertight. Their direction follows the same rule as for contours.
+	OpenContours [][]Vec3
+}
+
+// Contour is a closed polygon in a Layer. Outer contours enclosing material
+// are counter-clockwise when looking at the plane against its normal, i.e.
+// from the side the normal points to, holes are clockwise.
+type Contour struct {
+	// Points are the polygon's vertices. The last one is connected to the first.
+	Points []Vec3
+
+	// IsHole is true if the contour is enclosed by an odd number of other
+	// contours, so it is the border of empty space within material.
+	IsHole bool
+
+	// Parent is the index of the innermost contour in the same layer that
+	// encloses this one, or -1 if there is none.
+	Parent int
+}
+
+// Slice cuts s with parallel planes orthogonal to planeNormal, one for every
+// height, and returns one Layer per height in the same order. The plane of a
+// height h contains all points p with planeNormal.Dot(p) == h, after
+// planeNormal has been normalized.
+//
+// The contours are assembled from the segments where the triangles cross the
+// plane. Vertices lying exactly in the plane are treated as if they were
+// slightly above it, so every contour is well defined. Segments are matched
+// by the triangle edges they start and end on, so vertices have to be
+// exactly equal, like in Validate. Triangles are assumed to face outwards;
+// orientation and hole classification are nevertheless derived from how
+// contours are nested, not from the triangles' orientation.
+func (s *Solid) Slice(planeNormal Vec3, heights []float64) []Layer {
+	n := toVec64(planeNormal).unit()
+	layers := make([]Layer, len(heights))
+
+	// sweep through the heights in ascending order, keeping the triangles
+	// spanning the current height active
+	lower := make([]float64, len(s.Triangles))
+	upper := make([]float64, len(s.Triangles))
+	byLower := make([]int, len(s.Triangles))
+	for i := range s.Triangles {
+		lower[i], upper[i] = math.Inf(1), math.Inf(-1)
+		for _, v := range s.Triangles[i].Vertices {
+			d := n.dot(toVec64(v))
+			lower[i] = math.Min(lower[i], d)
+			upper[i] = math.Max(upper[i], d)
+		}
+		byLower[i] = i
+	}
+	sort.Slice(byLower, func(a, b int) bool { return lower[byLower[a]] < lower[byLower[b]] })
+	order := make([]int, len(heights))
+	for i := range order {
+		order[i] = i
+	}
+	sort.Slice(order, func(a, b int) bool { return heights[order[a]] < heights[order[b]] })
+
+	var active []int
+	next := 0
+	for _, l := range order {
+		h := heights[l]
+		for next < len(byLower) && lower[byLower[next]] <= h {
+			active = append(active, byLower[next])
+			next++
+		}
+		k := 0
+		for _, i := range active {
+			if upper[i] >= h {
+				active[k] = i
+				k++
+			}
+		}
+		active = active[:k]
+		layers[l] = s.sliceLayer(n, h, active)
+	}
+	return layers
+}
+
+// SliceZ slices s into layers of thickness layerHeight orthogonal to the z
+// axis, see Slice. The planes lie in the middle of each layer, the first
+// one at layerHeight / 2 above the lowest point of s.
+func (s *Solid) SliceZ(layerHeight float64) []Layer {
+	if len(s.Triangles) == 0 || !(layerHeight > 0) {
+		return nil
+	}
+	m := s.Measure()
+	var heights []float64
+	for h := float64(m.Min[2]) + layerHeight/2; h < float64(m.Max[2]); h += layerHeight {
+		heights = append(heights, h)
+	}
+	return s.Slice(Vec3{0, 0, 1}, heights)
+}
+
+// sliceSegment is the part of a triangle within a plane. It starts and
+// ends on the triangle edges given by their vertices in canonical order.
+type sliceSegment struct {
+	from, to [2]Vec3
+}
+
+// sliceLayer returns the layer cut from the given triangles of s by the
+// plane with unit normal n at height h.
+func (s *Solid) sliceLayer(n vec64, h float64, triangles []int) Layer {
+	layer := Layer{Normal: n.vec3(), Height: h}
+	var segments []sliceSegment
+	for _, i := range triangles {
+		t := &s.Triangles[i]
+		var above [3]bool
+		aboveCount := 0
+		for v := 0; v < 3; v++ {
+			above[v] = n.dot(toVec64(t.Vertices[v])) >= h
+			if above[v] {
+				aboveCount++
+			}
+		}
+		if aboveCount == 0 || aboveCount == 3 {
+			continue
+		}
+		// find the vertex alone on its side
+		lone := 0
+		for v := 0; v < 3; v++ {
+			if above[v] == (aboveCount == 1) {
+				lone = v
+			}
+		}
+		// for a lone vertex above the plane the segment runs from the edge
+		// after it to the edge before it, keeping material to its left
+		after := canonicalEdge(t.Vertices[lone], t.Vertices[(lone+1)%3])
+		before := canonicalEdge(t.Vertices[(lone+2)%3], t.Vertices[lone])
+		if aboveCount == 1 {
+			segments = append(segments, sliceSegment{from: after, to: before})
+		} else {
+			segments = append(segments, sliceSegment{from: before, to: after})
+		}
+	}
+
+	closed, open := chainSegments(segments)
+	point := func(e [2]Vec3) Vec3 {
+		return edgePlaneIntersection(e, n, h).vec3()
+	}
+	for _, chain := range closed {
+		var points []Vec3
+		for _, e := range chain {
+			p := point(e)
+			if len(points) == 0 || points[len(points)-1] != p {
+				points = append(points, p)
+			}
+		}
+		for len(points) > 1 && points[0] == points[len(points)-1] {
+			points = points[:len(points)-1]
+		}
+		if len(points) >= 3 {
+			layer.Contours = append(layer.Contours, Contour{Points: points})
+		}
+	}
+	for _, chain := range open {
+		var points []Vec3
+		for _, e := range chain {
+			p := point(e)
+			if len(points) == 0 || points[len(points)-1] != p {
+				points = append(points, p)
+			}
+		}
+		layer.OpenContours = append(layer.OpenContours, points)
+	}
+	classifyContours(layer.Contours, n)
+	return layer
+}
+
+// canonicalEdge returns the vertices of the edge between a and b in a
+// canonical order, so it is the same for both triangles sharing the edge.
+func canonicalEdge(a, b Vec3) [2]Vec3 {
+	for d := 0; d < 3; d++ {
+		if a[d] != b[d] {
+			if a[d] > b[d] {
+				return [2]Vec3{b, a}
+			}
+			break
+		}
+	}
+	return [2]Vec3{a, b}
+}
+
+// edgePlaneIntersection returns the point where the canonical edge e crosses
+// the plane with unit normal n at height h. As the edge is canonical, the
+// result is exactly the same for all triangles sharing it.
+func edgePlaneIntersection(e [2]Vec3, n vec64, h float64) vec64 {
+	a, b := toVec64(e[0]), toVec64(e[1])
+	da, db := n.dot(a)-h, n.dot(b)-h
+	if da == db {
+		return a
+	}
+	t := da / (da - db)
+	return a.add(b.sub(a).scale(t))
+}
+
+// chainSegments connects segments where one ends on the edge the other one
+// starts on. It returns the closed chains and the open ones, as the list of
+// edges they pass through. Open chains end with the edge their last segment
+// ends on.
+func chainSegments(segments []sliceSegment) (closed, open [][][2]Vec3) {
+	startingAt := make(map[[2]Vec3][]int, len(segments))
+	hasPredecessor := make(map[[2]Vec3]bool, len(segments))
+	for i, seg := range segments {
+		startingAt[seg.from] = append(startingAt[seg.from], i)
+		hasPredecessor[seg.to] = true
+	}
+	used := make([]bool, len(segments))
+	successor := func(i int) int {
+		for _, j := range startingAt[segments[i].to] {
+			if !used[j] {
+				return j
+			}
+		}
+		return -1
+	}
+	follow := func(start int) (chain [][2]Vec3, isClosed bool) {
+		i := start
+		for {
+			used[i] = true
+			chain = append(chain, segments[i].from)
+			j := successor(i)
+			if j < 0 {
+				if segments[i].to == segments[start].from {
+					return chain, true
+				}
+				return append(chain, segments[i].to), false
+			}
+			i = j
+		}
+	}
+
+	// open chains start at segments without predecessor
+	for i, seg := range segments {
+		if !used[i] && !hasPredecessor[seg.from] {
+			chain, _ := follow(i)
+			open = append(open, chain)
+		}
+	}
+	for i := range segments {
+		if !used[i] {
+			if chain, isClosed := follow(i); isClosed {
+				closed = append(closed, chain)
+			} else {
+				open = append(open, chain)
+			}
+		}
+	}
+	return closed, open
+}
+
+// classifyContours sets IsHole and Parent of all contours in the plane with
+// unit normal n, and orients them.
+func classifyContours(contours []Contour, n vec64) {
+	u, v := planeBasis(n)
+	polygons := make([][][2]float64, len(contours))
+	areas := make([]float64, len(contours))
+	for c := range contours {
+		polygons[c] = make([][2]float64, len(contours[c].Points))
+		for i, p := range contours[c].Points {
+			q := toVec64(p)
+			polygons[c][i] = [2]float64{q.dot(u), q.dot(v)}
+		}
+		areas[c] = polygonArea2(polygons[c])
+	}
+	for c := range contours {
+		contours[c].Parent = -1
+		depth := 0
+		for o := range contours {
+			if o == c || math.Abs(areas[o]) <= math.Abs(areas[c]) ||
+				!pointInPolygon2(polygons[c][0], polygons[o]) {
+				continue
+			}
+			depth++
+			if p := contours[c].Parent; p < 0 || math.Abs(areas[o]) < math.Abs(areas[p]) {
+				contours[c].Parent = o
+			}
+		}
+		contours[c].IsHole = depth%2 == 1
+		if (areas[c] < 0) != contours[c].IsHole {
+			reverseVec3s(contours[c].Points)
+		}
+	}
+}
+
+// polygonArea2 returns the signed area of a 2D polygon, positive if it is counter-clockwise.
+func polygonArea2(points [][2]float64) float64 {
+	var area float64
+	for i, p := range points {
+		q := points[(i+1)%len(points)]
+		area += p[0]*q[1] - q[0]*p[1]
+	}
+	return area / 2
+}
+
+// pointInPolygon2 returns true if p lies inside the 2D polygon, using the
+// even-odd rule.
+func pointInPolygon2(p [2]float64, polygon [][2]float64) bool {
+	inside := false
+	for i, a := range polygon {
+		b := polygon[(i+1)%len(polygon)]
+		if (a[1] > p[1]) != (b[1] > p[1]) &&
+			p[0] < a[0]+(p[1]-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+			inside = !inside
+		}
+	}
+	return inside
+}
+
+// reverseVec3s reverses the order of points.
+func reverseVec3s(points []Vec3) {
+	for i, j := 0, len(points)-1; i < j; i, j = i+1, j-1 {
+		points[i], points[j] = points[j], points[i]
+	}
+}
@@ -0,0 +1,93 @@
+package stl
+
+// Tests for planar slicing
+
+import (
+	"testing"
+)
+
+func TestSliceCube(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	layers := s.Slice(Vec3{0, 0, 2}, []float64{0.5, -1, 1, 0})
+	if len(layers) != 4 {
+		t.Fatalf("expected 4 layers, got %d", len(layers))
+	}
+	if layers[0].Normal != (Vec3{0, 0, 1}) || layers[0].Height != 0.5 {
+		t.Errorf("unexpected plane %v %g", layers[0].Normal, layers[0].Height)
+	}
+	for _, l := range []int{0, 2} {
+		layer := layers[l]
+		if len(layer.Contours) != 1 || len(layer.OpenContours) != 0 {
+			t.Fatalf("layer %d: expected one closed contour, got %v", l, layer)
+		}
+		c := layer.Contours[0]
+		if c.IsHole || c.Parent != -1 {
+			t.Errorf("layer %d: expected outer contour, got %+v", l, c)
+		}
+		for _, p := range c.Points {
+			if float64(p[2]) != layer.Height {
+				t.Errorf("layer %d: point %v not in plane", l, p)
+			}
+		}
+		if a := contourArea(c.Points); !almostEqual64(a, 1, 1e-6) {
+			t.Errorf("layer %d: expected counter-clockwise area 1, got %g", l, a)
+		}
+	}
+	for _, l := range []int{1, 3} {
+		if len(layers[l].Contours) != 0 || len(layers[l].OpenContours) != 0 {
+			t.Errorf("layer %d: expected no contours, got %v", l, layers[l])
+		}
+	}
+}
+
+func TestSliceHoles(t *testing.T) {
+	outer := makeTestCube(Vec3{0, 0, 0}, 3)
+	inner := makeTestCube(Vec3{1, 1, 0}, 1)
+	inner.Stretch(Vec3{1, 1, 5})
+	inner.Translate(Vec3{0, 0, -1})
+	hollow, err := outer.Difference(inner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	layers := hollow.SliceZ(1)
+	if len(layers) != 3 {
+		t.Fatalf("expected 3 layers, got %d", len(layers))
+	}
+	for l, layer := range layers {
+		if len(layer.Contours) != 2 {
+			t.Fatalf("layer %d: expected 2 contours, got %d", l, len(layer.Contours))
+		}
+		for _, c := range layer.Contours {
+			area := contourArea(c.Points)
+			if c.IsHole {
+				if c.Parent < 0 || layer.Contours[c.Parent].IsHole || !almostEqual64(area, -1, 1e-5) {
+					t.Errorf("layer %d: unexpected hole %+v with area %g", l, c, area)
+				}
+			} else if c.Parent != -1 || !almostEqual64(area, 9, 1e-5) {
+				t.Errorf("layer %d: unexpected outer contour %+v with area %g", l, c, area)
+			}
+		}
+	}
+}
+
+func TestSliceOpen(t *testing.T) {
+	s := makeTestCube(Vec3{0, 0, 0}, 1)
+	s.Triangles = s.Triangles[1:]
+	layer := s.Slice(Vec3{0, 0, 1}, []float64{0.5})[0]
+	if len(layer.Contours) != 0 || len(layer.OpenContours) != 1 {
+		t.Fatalf("expected one open contour, got %v", layer)
+	}
+	if n := len(layer.OpenContours[0]); n != 8 {
+		t.Errorf("expected 8 points in open contour, got %d", n)
+	}
+}
+
+// contourArea returns the signed area of a contour in a plane orthogonal
+// to the z axis, positive if it is counter-clockwise seen from above.
+func contourArea(points []Vec3) float64 {
+	projected := make([][2]float64, len(points))
+	for i, p := range points {
+		projected[i] = [2]float64{float64(p[0]), float64(p[1])}
+	}
+	return polygonArea2(projected)
+}
@@ -96,6 +96,31 @@ func (s *Solid) Measure() SolidMeasure {
 	return measure
 }
 
+// CheckFinite returns the indices of all triangles in s.Triangles that contain
+// NaN or ±Inf values in their normal vector or vertices. Such values make
+// the results of Measure, Validate, and most transformations meaningless.
+// Returns nil if all values are finite.
+func (s *Solid) CheckFinite() []int {
+	var indices []int
+	for i := range s.Triangles {
+		if !s.Triangles[i].isFinite() {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// firstNonFinite returns the index of the first triangle containing NaN
+// or ±Inf values, or -1 if there is none.
+func (s *Solid) firstNonFinite() int {
+	for i := range s.Triangles {
+		if !s.Triangles[i].isFinite() {
+			return i
+		}
+	}
+	return -1
+}
+
 // Transform applies a 4x4 transformation matrix to every vertex
 // and recalculates the normal for every triangle
 func (s *Solid) Transform(transformationMatrix *Mat4) {
@@ -351,14 +376,38 @@ func (m triangleErrorsMap) item(triangleIdx int) *TriangleErrors {
 
 const normalAngleTolerance = HalfPi
 
+// ValidateOptions control Solid.ValidateWithOptions.
+type ValidateOptions struct {
+	// VertexTolerance is the maximum difference per coordinate up to which
+	// vertices are considered equal, when matching edges and looking for
+	// equal vertices. This way, vertices of neighboring triangles that differ
+	// only by rounding errors, e.g. after Transform or from ASCII files with
+	// few digits, are not reported as open edges. Vertices are snapped to the
+	// first vertex within the tolerance, using a spatial grid. The default 0
+	// means that vertices have to be exactly equal.
+	VertexTolerance float32
+}
+
 // Validate looks for triangles that are really lines or dots, and for edges that
 // violate the vertex-to-vertex rule. Returns a map of errors by triangle
 // index that could be used to print out an error report.
 func (s *Solid) Validate() map[int]*TriangleErrors {
+	return s.ValidateWithOptions(ValidateOptions{})
+}
+
+// ValidateWithOptions works like Validate, using options to control the checks.
+func (s *Solid) ValidateWithOptions(options ValidateOptions) map[int]*TriangleErrors {
+	// The triangles used to compare vertices, s.Triangles itself if
+	// no tolerance is given
+	triangles := s.Triangles
+	if options.VertexTolerance > 0 {
+		triangles = s.snappedTriangles(options.VertexTolerance)
+	}
+
 	// Build up lookup from edge to triangle
 	e := newEdgeLookup()
-	for i := range s.Triangles {
-		t := &s.Triangles[i]
+	for i := range triangles {
+		t := &triangles[i]
 		for vertex1 := 0; vertex1 < 3; vertex1++ {
 			vertex2 := (vertex1 + 1) % 3
 			e.InsertEdge(i, t.Vertices[vertex1], t.Vertices[vertex2])
@@ -370,15 +419,15 @@ func (s *Solid) Validate() map[int]*TriangleErrors {
 	// and that the same edge is not used by another triangle.
 	triangleErrors := make(triangleErrorsMap)
 
-	for i := range s.Triangles {
-		t := &s.Triangles[i]
+	for i := range triangles {
+		t := &triangles[i]
 		// check for equal vertices
 		if t.hasEqualVertices() {
 			triangleErrors.item(i).HasEqualVertices = true
 		}
 
 		// check if normal matches vertices
-		if !t.checkNormal(normalAngleTolerance) {
+		if !s.Triangles[i].checkNormal(normalAngleTolerance) {
 			triangleErrors.item(i).NormalDoesNotMatch = true
 		}
 
@@ -409,3 +458,17 @@ func (s *Solid) Validate() map[int]*TriangleErrors {
 
 	return triangleErrors
 }
+
+// snappedTriangles returns a copy of s.Triangles, where every vertex is
+// replaced by the first vertex found within tol, see vertexWelder.
+func (s *Solid) snappedTriangles(tol float32) []Triangle {
+	w := newVertexWelder(tol, len(s.Triangles)/2+3)
+	triangles := make([]Triangle, len(s.Triangles))
+	for i := range s.Triangles {
+		triangles[i] = s.Triangles[i]
+		for v := 0; v < 3; v++ {
+			triangles[i].Vertices[v] = w.vertices[w.index(s.Triangles[i].Vertices[v])]
+		}
+	}
+	return triangles
+}
@@ -4,6 +4,7 @@ package stl
 // Could be more exhaustive.
 
 import (
+	"math"
 	"testing"
 )
 
@@ -166,3 +167,28 @@ func BenchmarkTransform(b *testing.B) {
 		solid.Transform(&rotationMatrix)
 	}
 }
+
+func TestValidateWithOptions(t *testing.T) {
+	solid := makeTestCube(Vec3{0, 0, 0}, 1)
+	// shift a vertex of a single triangle by a rounding error
+	v := &solid.Triangles[5].Vertices[1]
+	v[0] = math.Nextafter32(v[0], 2)
+	if errors := solid.Validate(); len(errors) == 0 {
+		t.Error("expected open edges without tolerance")
+	}
+	if errors := solid.ValidateWithOptions(ValidateOptions{VertexTolerance: 1e-6}); len(errors) != 0 {
+		t.Errorf("expected no errors with tolerance, got %v", errors)
+	}
+	// a triangle collapsing within the tolerance
+	solid.Triangles = append(solid.Triangles, Triangle{
+		Normal:   Vec3{0, 0, 1},
+		Vertices: [3]Vec3{{5, 5, 5}, {6, 5, 5}, {5, 5, 5.000002}},
+	})
+	if errors := solid.Validate(); errors[12] == nil || errors[12].HasEqualVertices {
+		t.Error("Triangle 12 must only have equal vertices within tolerance")
+	}
+	errors := solid.ValidateWithOptions(ValidateOptions{VertexTolerance: 1e-5})
+	if errors[12] == nil || !errors[12].HasEqualVertices {
+		t.Error("Failed to detect HasEqualVertices within tolerance in triangle 12")
+	}
+}
@@ -6,6 +6,7 @@ package stl
 import (
 	"bytes"
 	"io/ioutil"
+	"math"
 	"testing"
 )
 
@@ -50,6 +51,95 @@ func makeTestSolid() *Solid {
 	}
 }
 
+// makeTestCube returns a closed cube with edge length size and its minimum
+// corner at min, made of 12 triangles with outward normals.
+func makeTestCube(min Vec3, size float32) *Solid {
+	faces := [6][4]Vec3{
+		{{0, 0, 0}, {0, 0, 1}, {0, 1, 1}, {0, 1, 0}}, // -x
+		{{1, 0, 0}, {1, 1, 0}, {1, 1, 1}, {1, 0, 1}}, // +x
+		{{0, 0, 0}, {1, 0, 0}, {1, 0, 1}, {0, 0, 1}}, // -y
+		{{0, 1, 0}, {0, 1, 1}, {1, 1, 1}, {1, 1, 0}}, // +y
+		{{0, 0, 0}, {0, 1, 0}, {1, 1, 0}, {1, 0, 0}}, // -z
+		{{0, 0, 1}, {1, 0, 1}, {1, 1, 1}, {0, 1, 1}}, // +z
+	}
+	s := &Solid{Name: "Cube", IsAscii: true}
+	for _, f := range faces {
+		var c [4]Vec3
+		for i := range f {
+			c[i] = f[i].MultScalar(float64(size)).Add(min)
+		}
+		for _, vs := range [2][3]Vec3{{c[0], c[1], c[2]}, {c[0], c[2], c[3]}} {
+			t := Triangle{Vertices: vs}
+			t.recalculateNormal()
+			s.Triangles = append(s.Triangles, t)
+		}
+	}
+	return s
+}
+
+// makeTestTorus returns a closed torus around the z axis with major radius R
+// and minor radius r, made of 2*n*m triangles with outward normals.
+func makeTestTorus(R, r float64, n, m int) *Solid {
+	point := func(i, j int) Vec3 {
+		u := TwoPi * float64(i%n) / float64(n)
+		v := TwoPi * float64(j%m) / float64(m)
+		return Vec3{
+			float32((R + r*math.Cos(v)) * math.Cos(u)),
+			float32((R + r*math.Cos(v)) * math.Sin(u)),
+			float32(r * math.Sin(v)),
+		}
+	}
+	s := &Solid{Name: "Torus", IsAscii: true}
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			a, b, c, d := point(i, j), point(i+1, j), point(i+1, j+1), point(i, j+1)
+			for _, vs := range [2][3]Vec3{{a, b, c}, {a, c, d}} {
+				t := Triangle{Vertices: vs}
+				t.recalculateNormal()
+				s.Triangles = append(s.Triangles, t)
+			}
+		}
+	}
+	return s
+}
+
+// makeTestSphere returns a closed UV sphere with the given centre and radius,
+// made of n segments around the z axis and m rings, with outward normals.
+func makeTestSphere(centre Vec3, radius float64, n, m int) *Solid {
+	point := func(i, j int) Vec3 {
+		u := TwoPi * float64(i%n) / float64(n)
+		v := Pi * float64(j) / float64(m)
+		return Vec3{
+			centre[0] + float32(radius*math.Sin(v)*math.Cos(u)),
+			centre[1] + float32(radius*math.Sin(v)*math.Sin(u)),
+			centre[2] + float32(radius*math.Cos(v)),
+		}
+	}
+	s := &Solid{Name: "Sphere", IsAscii: true}
+	for i := 0; i < n; i++ {
+		for j := 0; j < m; j++ {
+			a, b, c, d := point(i, j), point(i, j+1), point(i+1, j+1), point(i+1, j)
+			if j == 0 {
+				a = Vec3{centre[0], centre[1], centre[2] + float32(radius)}
+				d = a
+			}
+			if j == m-1 {
+				b = Vec3{centre[0], centre[1], centre[2] - float32(radius)}
+				c = b
+			}
+			for _, vs := range [2][3]Vec3{{a, b, c}, {a, c, d}} {
+				t := Triangle{Vertices: vs}
+				if t.hasEqualVertices() {
+					continue
+				}
+				t.recalculateNormal()
+				s.Triangles = append(s.Triangles, t)
+			}
+		}
+	}
+	return s
+}
+
 func TestSolidSameOrderEqual(t *testing.T) {
 	testSolid := makeTestSolid()
 	if !testSolid.sameOrderAlmostEqual(testSolid) {
@@ -57,6 +147,13 @@ func TestSolidSameOrderEqual(t *testing.T) {
 	}
 }
 
+func TestMakeTestCube(t *testing.T) {
+	errors := makeTestCube(Vec3{1, 2, 3}, 2).Validate()
+	if len(errors) != 0 {
+		t.Errorf("test cube is not valid: %v", errors)
+	}
+}
+
 func cmpFiles(filename1, filename2 string) (eq bool, err error) {
 	data1, err1 := ioutil.ReadFile(filename1)
 	if err1 != nil {
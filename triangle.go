@@ -65,3 +65,25 @@ func (t *Triangle) checkNormal(tol float64) bool {
 	calculatedNormal := t.calculateNormal()
 	return t.Normal.Angle(calculatedNormal) < tol
 }
+
+// Returns true if neither the normal nor any vertex contains NaN or ±Inf.
+func (t *Triangle) isFinite() bool {
+	return t.Normal.isFinite() &&
+		t.Vertices[0].isFinite() &&
+		t.Vertices[1].isFinite() &&
+		t.Vertices[2].isFinite()
+}
+
+// Sets every NaN or ±Inf value in the normal and the vertices to 0.
+func (t *Triangle) zeroNonFinite() {
+	t.Normal.zeroNonFinite()
+	t.Vertices[0].zeroNonFinite()
+	t.Vertices[1].zeroNonFinite()
+	t.Vertices[2].zeroNonFinite()
+}
+
+// Area returns the area of the triangle, calculated in double precision.
+func (t *Triangle) Area() float64 {
+	a := toVec64(t.Vertices[0])
+	return 0.5 * toVec64(t.Vertices[1]).sub(a).cross(toVec64(t.Vertices[2]).sub(a)).len()
+}
@@ -88,3 +88,17 @@ func (vec Vec3) Angle(o Vec3) float64 {
 
 	return math.Acos(cosAngle)
 }
+
+// isFinite returns true, if no coordinate of vec is NaN or ±Inf.
+func (vec Vec3) isFinite() bool {
+	return isFinite32(vec[0]) && isFinite32(vec[1]) && isFinite32(vec[2])
+}
+
+// zeroNonFinite sets every coordinate of vec that is NaN or ±Inf to 0.
+func (vec *Vec3) zeroNonFinite() {
+	for d := 0; d < 3; d++ {
+		if !isFinite32(vec[d]) {
+			vec[d] = 0
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package stl
+
+// This file contains a double precision 3D vector used internally for
+// calculations, where the single precision of Vec3 is not sufficient.
+
+import (
+	"math"
+)
+
+// vec64 is the float64 counterpart of Vec3.
+type vec64 [3]float64
+
+// toVec64 converts v to double precision.
+func toVec64(v Vec3) vec64 {
+	return vec64{float64(v[0]), float64(v[1]), float64(v[2])}
+}
+
+// vec3 converts v to single precision.
+func (v vec64) vec3() Vec3 {
+	return Vec3{float32(v[0]), float32(v[1]), float32(v[2])}
+}
+
+func (v vec64) add(o vec64) vec64 {
+	return vec64{v[0] + o[0], v[1] + o[1], v[2] + o[2]}
+}
+
+func (v vec64) sub(o vec64) vec64 {
+	return vec64{v[0] - o[0], v[1] - o[1], v[2] - o[2]}
+}
+
+func (v vec64) scale(f float64) vec64 {
+	return vec64{v[0] * f, v[1] * f, v[2] * f}
+}
+
+func (v vec64) dot(o vec64) float64 {
+	return v[0]*o[0] + v[1]*o[1] + v[2]*o[2]
+}
+
+func (v vec64) cross(o vec64) vec64 {
+	return vec64{
+		v[1]*o[2] - v[2]*o[1],
+		v[2]*o[0] - v[0]*o[2],
+		v[0]*o[1] - v[1]*o[0],
+	}
+}
+
+func (v vec64) len() float64 {
+	return math.Sqrt(v.dot(v))
+}
+
+// unit returns v scaled to length 1, or v itself if its length is 0.
+func (v vec64) unit() vec64 {
+	l := v.len()
+	if l == 0 {
+		return v
+	}
+	return v.scale(1 / l)
+}
+
+// planeBasis returns two unit vectors u and v orthogonal to the unit vector n,
+// so that u, v, n form a right-handed system. For n = [0 0 1], u and v are
+// the x and y axis.
+func planeBasis(n vec64) (u, v vec64) {
+	// start with the axis least aligned with n
+	helper := vec64{1, 0, 0}
+	if math.Abs(n[1]) < math.Abs(n[0]) && math.Abs(n[1]) <= math.Abs(n[2]) {
+		helper = vec64{0, 1, 0}
+	} else if math.Abs(n[2]) < math.Abs(n[0]) && math.Abs(n[2]) < math.Abs(n[1]) {
+		helper = vec64{0, 0, 1}
+	}
+	u = helper.sub(n.scale(helper.dot(n))).unit()
+	v = n.cross(u)
+	return u, v
+}
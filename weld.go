@@ -0,0 +1,72 @@
+package stl
+
+// This file contains vertex welding, i.e. mapping equal or nearly equal
+// vertices to a common index.
+
+import (
+	"math"
+)
+
+// vertexWelder assigns an index to every distinct vertex. Vertices that are
+// exactly equal always get the same index. If tol > 0, a vertex also gets
+// the index of an earlier vertex, if no coordinate differs more than tol.
+// Nearby vertices are found using a uniform grid of cell size tol, so only
+// the 27 cells around a vertex have to be searched.
+type vertexWelder struct {
+	tol      float32
+	vertices []Vec3
+	exact    map[Vec3]uint32
+	grid     map[[3]int64][]uint32
+}
+
+func newVertexWelder(tol float32, capacity int) *vertexWelder {
+	w := vertexWelder{
+		tol:      tol,
+		vertices: make([]Vec3, 0, capacity),
+		exact:    make(map[Vec3]uint32, capacity),
+	}
+	if tol > 0 {
+		w.grid = make(map[[3]int64][]uint32, capacity)
+	}
+	return &w
+}
+
+// cell returns the grid cell containing v.
+func (w *vertexWelder) cell(v Vec3) [3]int64 {
+	var c [3]int64
+	for d := 0; d < 3; d++ {
+		c[d] = int64(math.Floor(float64(v[d]) / float64(w.tol)))
+	}
+	return c
+}
+
+// index returns the index of the vertex v is welded to, adding v as
+// a new vertex if there is none.
+func (w *vertexWelder) index(v Vec3) uint32 {
+	if idx, found := w.exact[v]; found {
+		return idx
+	}
+	var c [3]int64
+	if w.tol > 0 {
+		c = w.cell(v)
+		for dx := int64(-1); dx <= 1; dx++ {
+			for dy := int64(-1); dy <= 1; dy++ {
+				for dz := int64(-1); dz <= 1; dz++ {
+					for _, idx := range w.grid[[3]int64{c[0] + dx, c[1] + dy, c[2] + dz}] {
+						if w.vertices[idx].AlmostEqual(v, w.tol) {
+							w.exact[v] = idx
+							return idx
+						}
+					}
+				}
+			}
+		}
+	}
+	idx := uint32(len(w.vertices))
+	w.vertices = append(w.vertices, v)
+	w.exact[v] = idx
+	if w.tol > 0 {
+		w.grid[c] = append(w.grid[c], idx)
+	}
+	return idx
+}
@@ -8,9 +8,28 @@ import (
 	"math"
 )
 
+// maxBinaryTriangleCount is the largest number of triangles the uint32
+// triangle count in the binary header can hold. Only changed by tests.
+var maxBinaryTriangleCount uint64 = math.MaxUint32
+
+// exceedsBinaryLimit returns true if s has too many triangles to be
+// written in binary STL.
+func (s *Solid) exceedsBinaryLimit() bool {
+	return uint64(len(s.Triangles)) > maxBinaryTriangleCount
+}
+
 // Write solid in binary STL into an io.Writer.
-// Does not check whether len(solid.Triangles) fits into uint32.
-func writeSolidBinary(w io.Writer, solid *Solid) error {
+// Fails before writing anything if len(solid.Triangles) does not fit into uint32.
+func writeSolidBinary(w io.Writer, solid *Solid, options *WriteOptions) error {
+	if solid.exceedsBinaryLimit() {
+		return &TooManyTrianglesError{Count: len(solid.Triangles)}
+	}
+	if !options.AllowNonFinite {
+		if i := solid.firstNonFinite(); i >= 0 {
+			return &NonFiniteError{TriangleIndex: i}
+		}
+	}
+
 	headerBuf := make([]byte, binaryHeaderSize)
 	if solid.BinaryHeader == nil {
 		// use name if no binary header set
@@ -0,0 +1,71 @@
+package stl
+
+// This file defines functions to emit drawings as DXF files.
+
+import (
+	"fmt"
+	"io"
+)
+
+// WriteDXF writes the drawing in the DXF R12 format to w, every polyline as a
+// 2D POLYLINE entity on layer "0". Coordinates are multiplied by
+// options.Scale, the other options do not apply to DXF.
+func (d *Drawing) WriteDXF(w io.Writer, options DrawingOptions) error {
+	scale := options.scale()
+	min, max := d.bounds()
+	dw := dxfWriter{w: w}
+	dw.group(0, "SECTION")
+	dw.group(2, "HEADER")
+	dw.group(9, "$ACADVER")
+	dw.group(1, "AC1009")
+	dw.group(9, "$EXTMIN")
+	dw.point(min[0]*scale, min[1]*scale)
+	dw.group(9, "$EXTMAX")
+	dw.point(max[0]*scale, max[1]*scale)
+	dw.group(0, "ENDSEC")
+
+	dw.group(0, "SECTION")
+	dw.group(2, "ENTITIES")
+	for _, p := range d.Polylines {
+		if len(p.Points) == 0 {
+			continue
+		}
+		flags := "0"
+		if p.Closed {
+			flags = "1"
+		}
+		dw.group(0, "POLYLINE")
+		dw.group(8, "0")
+		dw.group(66, "1")
+		dw.group(70, flags)
+		dw.point(0, 0)
+		for _, q := range p.Points {
+			dw.group(0, "VERTEX")
+			dw.group(8, "0")
+			dw.point(q[0]*scale, q[1]*scale)
+		}
+		dw.group(0, "SEQEND")
+		dw.group(8, "0")
+	}
+	dw.group(0, "ENDSEC")
+	dw.group(0, "EOF")
+	return dw.err
+}
+
+// dxfWriter writes DXF group codes and values, remembering the first error.
+type dxfWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (dw *dxfWriter) group(code int, value string) {
+	if dw.err == nil {
+		_, dw.err = fmt.Fprintf(dw.w, "%d\n%s\n", code, value)
+	}
+}
+
+// point writes the coordinates of a 2D point.
+func (dw *dxfWriter) point(x, y float64) {
+	dw.group(10, formatFloat(x))
+	dw.group(20, formatFloat(y))
+}
@@ -0,0 +1,80 @@
+package stl
+
+// This file defines functions to emit drawings as SVG files.
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var svgUnits = map[string]bool{"mm": true, "cm": true, "in": true, "pt": true, "pc": true, "px": true}
+
+// WriteSVG writes the drawing as SVG to w. The size of the SVG document is
+// the size of the scaled drawing plus the margin, in options.Unit. The y
+// axis points upwards like in the drawing, not downwards like in SVG.
+func (d *Drawing) WriteSVG(w io.Writer, options DrawingOptions) error {
+	scale, unit := options.scale(), options.Unit
+	if unit == "" {
+		unit = "mm"
+	}
+	if !svgUnits[unit] {
+		return fmt.Errorf("unsupported SVG unit %q", unit)
+	}
+	strokeWidth := options.StrokeWidth
+	if strokeWidth == 0 {
+		strokeWidth = 0.1
+	}
+
+	min, max := d.bounds()
+	width := (max[0]-min[0])*scale + 2*options.Margin
+	height := (max[1]-min[1])*scale + 2*options.Margin
+	_, err := fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<svg xmlns=\"http://www.w3.org/2000/svg\" version=\"1.1\" width=\"%s%s\" height=\"%s%s\" viewBox=\"0 0 %s %s\">\n"+
+		"<g fill=\"none\" stroke=\"black\" stroke-width=\"%s\" stroke-linejoin=\"round\">\n",
+		formatFloat(width), unit, formatFloat(height), unit, formatFloat(width), formatFloat(height), formatFloat(strokeWidth))
+	if err != nil {
+		return err
+	}
+
+	for _, p := range d.Polylines {
+		if len(p.Points) == 0 {
+			continue
+		}
+		var path strings.Builder
+		for i, q := range p.Points {
+			if i == 0 {
+				path.WriteString("M")
+			} else {
+				path.WriteString(" L")
+			}
+			x := (q[0]-min[0])*scale + options.Margin
+			y := (max[1]-q[1])*scale + options.Margin
+			path.WriteString(formatFloat(x) + " " + formatFloat(y))
+		}
+		if p.Closed {
+			path.WriteString(" Z")
+		}
+		if _, err = fmt.Fprintf(w, "<path d=\"%s\"/>\n", path.String()); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write([]byte("</g>\n</svg>\n"))
+	return err
+}
+
+// scale returns the scale factor to use.
+func (options *DrawingOptions) scale() float64 {
+	if options.Scale == 0 {
+		return 1
+	}
+	return options.Scale
+}
+
+// formatFloat formats f as compact as possible without exponent, as
+// expected by SVG and DXF readers.
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}